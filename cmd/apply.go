@@ -4,43 +4,154 @@ import (
 	"fmt"
 	"github.com/DimitarPetrov/printracer/tracing"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
 	"os"
 )
 
 type ApplyCmd struct {
 	instrumenter   tracing.CodeInstrumenter
 	importsGroomer tracing.ImportsGroomer
+	store          tracing.FileStore
+	out            io.Writer
+
+	options    tracing.Options
+	dryRun     bool
+	overlay    bool
+	overlayDir string
+	targets    []target
 }
 
-func NewApplyCmd(instrumenter tracing.CodeInstrumenter, importsGroomer tracing.ImportsGroomer) *ApplyCmd {
+func NewApplyCmd(instrumenter tracing.CodeInstrumenter, importsGroomer tracing.ImportsGroomer, store tracing.FileStore) *ApplyCmd {
 	return &ApplyCmd{
 		instrumenter:   instrumenter,
 		importsGroomer: importsGroomer,
+		store:          store,
+		out:            os.Stdout,
 	}
 }
 
 func (ac *ApplyCmd) Prepare() *cobra.Command {
-	return &cobra.Command{
-		Use:          "apply",
-		Aliases:      []string{"a"},
-		Short:        "Instruments a directory of go files",
+	result := &cobra.Command{
+		Use:     "apply [directories | files | packages]",
+		Aliases: []string{"a"},
+		Short:   "Instruments a directory of go files",
+		Long: `Instruments go files. Arguments can be directories, go files, import paths of packages in the current module
+and patterns like ./internal/... which include all the subdirectories.
+Without arguments the current directory and all of its subdirectories are instrumented.`,
 		PreRunE:      commonPreRunE(ac),
 		RunE:         commonRunE(ac),
 		SilenceUsage: true,
 	}
+
+	addInstrumentationFlags(result.Flags(), &ac.options)
+	result.Flags().StringVar(&ac.options.Output, "sink", tracing.StdoutOutput, "where the instrumented code writes the trace: stdout, stderr or a path of a file the trace is appended to. Overridden at runtime by PRINTRACER_OUTPUT environment variable.")
+	result.Flags().BoolVar(&ac.dryRun, "dry-run", false, "print a unified diff of every file and a summary of the functions which would be instrumented without writing anything")
+	result.Flags().BoolVar(&ac.overlay, "overlay", false, "write the instrumented files into an overlay directory instead of modifying the working tree, along with an overlay file for go build -overlay and go test -overlay")
+	result.Flags().StringVar(&ac.overlayDir, "overlay-dir", "", "directory the overlay is written to with --overlay. Defaults to a directory in the user cache directory unique per working directory.")
+	return result
 }
 
-func (ac *ApplyCmd) Run() error {
+// addInstrumentationFlags adds the flags controlling what gets instrumented and printed by the instrumentation.
+func addInstrumentationFlags(flags *pflag.FlagSet, options *tracing.Options) {
+	flags.BoolVar(&options.PrintReceivers, "receivers", false, "print method receivers on function entry. Pointer receivers are printed by address and value receivers by value.")
+	flags.StringVar(&options.Format, "format", tracing.TextFormat, "format of the trace printed by the instrumented code: text or json (one JSON object per line)")
+	flags.BoolVar(&options.TypeAware, "types", false, "load the packages with type information and print arguments according to their types: strings quoted, errors by their messages, contexts as a short summary, functions and channels by their types and byte slices in hex.")
+	flags.BoolVar(&options.IncludeTests, "include-tests", false, "instrument _test.go files as well. Arguments of type *testing.T, *testing.B, *testing.F and testing.TB are printed by the name of the running test.")
+	flags.BoolVar(&options.IncludeGenerated, "include-generated", false, "instrument generated files (with a // Code generated ... DO NOT EDIT. comment before the package clause) as well.")
+	flags.StringArrayVar(&options.Redact, "redact", tracing.DefaultRedactRules, "print parameters, results and receivers matching the rule as <redacted>. Rules are globs (or regular expressions when prefixed with re:) matched against their names, or against their types as written in the source when prefixed with type:, e.g. type:*auth.Credentials. Can be repeated, replacing the defaults, which match names ending with password, secret or token case-insensitively (but not e.g. tokens) or ending with Key (but not key).")
+	flags.IntVar(&options.MaxArgLen, "max-arg-len", 0, "maximum length in bytes of a printed argument, receiver or result. Longer values are truncated and marked with the number of omitted bytes, e.g. [1 2 3...(+1234 bytes). 0 means no limit. Overridden by PRINTRACER_MAX_ARG_LEN environment variable at runtime.")
+	flags.StringVar(&options.CallIDs, "call-ids", tracing.CounterCallIDs, "strategy of the call IDs: counter (unique within the traced process), process (counter prefixed with the ID of the traced process) or uuid7 (time-ordered UUIDs)")
+	flags.IntVar(&options.SampleRate, "sample", 0, "trace 1 in N calls of every function. Calls made within a call which is not traced are not traced either, so that sampled traces are complete trees.")
+	flags.IntVar(&options.RateLimit, "rate-limit", 0, "trace at most N calls of every function per second. 0 means no limit.")
+	flags.IntVar(&options.MaxEvents, "max-events", 0, "stop tracing new calls once N events were written. The returns of the calls in progress are still traced. 0 means no limit.")
+	flags.StringArrayVar(&options.Include, "include", nil, "instrument only functions matching the pattern. Patterns are globs (or regular expressions when prefixed with re:) matched against function names like foo, T.String or main.T.String, import paths of packages in the module like github.com/org/repo/internal/** and file paths like internal/generated/**. Can be repeated.")
+	flags.StringArrayVar(&options.Exclude, "exclude", nil, "do not instrument functions matching the pattern. Takes precedence over --include. Can be repeated.")
+}
+
+func validateInstrumentationOptions(options tracing.Options) error {
+	if options.Format != tracing.TextFormat && options.Format != tracing.JSONFormat {
+		return fmt.Errorf("unsupported trace format %s: expected %s or %s", options.Format, tracing.TextFormat, tracing.JSONFormat)
+	}
+	if options.CallIDs != tracing.CounterCallIDs && options.CallIDs != tracing.ProcessCallIDs && options.CallIDs != tracing.UUIDv7CallIDs {
+		return fmt.Errorf("unsupported call IDs %s: expected %s, %s or %s", options.CallIDs, tracing.CounterCallIDs, tracing.ProcessCallIDs, tracing.UUIDv7CallIDs)
+	}
+	if options.MaxArgLen < 0 {
+		return fmt.Errorf("invalid --max-arg-len %d: expected a non-negative number of bytes", options.MaxArgLen)
+	}
+	if options.SampleRate < 0 || options.RateLimit < 0 || options.MaxEvents < 0 {
+		return fmt.Errorf("invalid sampling --sample %d --rate-limit %d --max-events %d: expected non-negative numbers", options.SampleRate, options.RateLimit, options.MaxEvents)
+	}
+	return nil
+}
+
+func (ac *ApplyCmd) Validate(args []string) error {
+	if err := validateInstrumentationOptions(ac.options); err != nil {
+		return err
+	}
+	if ac.dryRun && ac.overlay {
+		return fmt.Errorf("--dry-run and --overlay cannot be used together")
+	}
+	if len(ac.overlayDir) > 0 && !ac.overlay {
+		return fmt.Errorf("--overlay-dir requires --overlay")
+	}
+
 	wd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("error getting current working directory: %v", err)
 	}
+	ac.targets, err = resolveTargets(wd, args)
+	return err
+}
 
-	return mapDirectory(wd, func(path string) error {
-		err := ac.instrumenter.InstrumentDirectory(path)
+func (ac *ApplyCmd) Run() error {
+	err := instrumentTargets(ac.instrumenter, ac.importsGroomer, ac.store, ac.targets, ac.options)
+	if err == nil && ac.overlay {
+		return ac.writeOverlay()
+	}
+	return finishChanges(ac.store, err, ac.dryRun, ac.out)
+}
+
+func (ac *ApplyCmd) writeOverlay() error {
+	dir := ac.overlayDir
+	if len(dir) == 0 {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("error getting current working directory: %v", err)
+		}
+		if dir, err = defaultOverlayDir(wd); err != nil {
+			return err
+		}
+	}
+
+	overlayFile, err := ac.store.WriteOverlay(dir)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(ac.out, "Overlay written to %s. Build or test the instrumented code with e.g.:\n  go build -overlay %s ./...\n", overlayFile, overlayFile)
+	return err
+}
+
+// instrumentTargets instruments the targets in the store without committing the changes.
+func instrumentTargets(instrumenter tracing.CodeInstrumenter, importsGroomer tracing.ImportsGroomer, store tracing.FileStore, targets []target, options tracing.Options) error {
+	importsToRemove := tracing.InstrumentationImports()
+	return mapTargets(targets, func(path string) error {
+		err := instrumenter.InstrumentDirectory(path, options)
+		if err != nil {
+			return err
+		}
+		return importsGroomer.RemoveUnusedImportFromDirectory(path, importsToRemove)
+	}, func(path string) error {
+		fset, pkg, err := parseFilePackage(store, path)
 		if err != nil {
 			return err
 		}
-		return ac.importsGroomer.RemoveUnusedImportFromDirectory(path, map[string]string{"fmt": "", "runtime": "rt", "rand": ""}) // TODO: flag for import aliases
+		if err := instrumenter.InstrumentPackage(fset, pkg, options); err != nil {
+			return err
+		}
+		if fset, pkg, err = parseFilePackage(store, path); err != nil {
+			return err
+		}
+		return importsGroomer.RemoveUnusedImportFromPackage(fset, pkg, importsToRemove)
 	})
 }
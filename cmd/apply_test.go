@@ -1,15 +1,22 @@
 package cmd
 
 import (
+	"bytes"
 	"errors"
+	"github.com/DimitarPetrov/printracer/tracing"
 	"github.com/DimitarPetrov/printracer/tracing/tracingfakes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
 func TestApplyCmd(t *testing.T) {
 	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
 	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
-	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer).Prepare()
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
 
 	if err := cmd.Execute(); err != nil {
 		t.Fatal(err)
@@ -19,7 +26,8 @@ func TestApplyCmd(t *testing.T) {
 func TestApplyCmdReturnsErrorWhenInstrumenterReturnError(t *testing.T) {
 	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
 	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
-	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer).Prepare()
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
 
 	expectedErr := errors.New("error")
 	fakeInstrumenter.InstrumentDirectoryReturns(expectedErr)
@@ -32,7 +40,8 @@ func TestApplyCmdReturnsErrorWhenInstrumenterReturnError(t *testing.T) {
 func TestApplyCmdReturnsErrorWhenImportsGroomerReturnError(t *testing.T) {
 	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
 	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
-	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer).Prepare()
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
 
 	expectedErr := errors.New("error")
 	fakeImportsGroomer.RemoveUnusedImportFromDirectoryReturns(expectedErr)
@@ -41,3 +50,239 @@ func TestApplyCmdReturnsErrorWhenImportsGroomerReturnError(t *testing.T) {
 		t.Error("Assertion failed!")
 	}
 }
+
+func TestApplyCmdPassesFormatToInstrumenter(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+	cmd.SetArgs([]string{"--format", "json"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, opts := fakeInstrumenter.InstrumentDirectoryArgsForCall(0); opts.Format != tracing.JSONFormat {
+		t.Errorf("Assertion failed! Expected format %s but got %s", tracing.JSONFormat, opts.Format)
+	}
+}
+
+func TestApplyCmdReturnsErrorOnUnsupportedFormat(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+	cmd.SetArgs([]string{"--format", "xml"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error to have occured!")
+	}
+	if fakeInstrumenter.InstrumentDirectoryCallCount() != 0 {
+		t.Error("Assertion failed! Expected no instrumentation with unsupported format")
+	}
+}
+
+func TestApplyCmdReturnsErrorOnNegativeMaxArgLen(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+	cmd.SetArgs([]string{"--max-arg-len", "-1"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error to have occured!")
+	}
+	if fakeInstrumenter.InstrumentDirectoryCallCount() != 0 {
+		t.Error("Assertion failed! Expected no instrumentation with negative max arg len")
+	}
+}
+
+func TestApplyCmdPassesSamplingToInstrumenter(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+	cmd.SetArgs([]string{"--sample", "100", "--rate-limit", "10", "--max-events", "1000"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, opts := fakeInstrumenter.InstrumentDirectoryArgsForCall(0); opts.SampleRate != 100 || opts.RateLimit != 10 || opts.MaxEvents != 1000 {
+		t.Errorf("Assertion failed! Expected sampling 100, 10, 1000 but got %d, %d, %d", opts.SampleRate, opts.RateLimit, opts.MaxEvents)
+	}
+}
+
+func TestApplyCmdReturnsErrorOnNegativeSampling(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+	cmd.SetArgs([]string{"--rate-limit", "-1"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error to have occured!")
+	}
+	if fakeInstrumenter.InstrumentDirectoryCallCount() != 0 {
+		t.Error("Assertion failed! Expected no instrumentation with negative sampling")
+	}
+}
+
+func TestApplyCmdReturnsErrorOnUnsupportedCallIDs(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+	cmd.SetArgs([]string{"--call-ids", "uuid4"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error to have occured!")
+	}
+	if fakeInstrumenter.InstrumentDirectoryCallCount() != 0 {
+		t.Error("Assertion failed! Expected no instrumentation with unsupported call IDs")
+	}
+}
+
+func TestApplyCmdInstrumentsOnlyTargets(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+	fakeFileStore.ReadFileStub = ioutil.ReadFile
+	cmd.SetArgs([]string{".", "apply.go"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fakeInstrumenter.InstrumentDirectoryCallCount() != 1 {
+		t.Fatalf("Assertion failed! Expected only the current directory to be instrumented but got %d calls", fakeInstrumenter.InstrumentDirectoryCallCount())
+	}
+	if path, _ := fakeInstrumenter.InstrumentDirectoryArgsForCall(0); path != wd {
+		t.Errorf("Assertion failed! Expected directory %s but got %s", wd, path)
+	}
+	if fakeInstrumenter.InstrumentPackageCallCount() != 1 {
+		t.Fatalf("Assertion failed! Expected a single file to be instrumented but got %d calls", fakeInstrumenter.InstrumentPackageCallCount())
+	}
+	if _, pkg, _ := fakeInstrumenter.InstrumentPackageArgsForCall(0); pkg.Files[filepath.Join(wd, "apply.go")] == nil || len(pkg.Files) != 1 {
+		t.Errorf("Assertion failed! Expected package of apply.go only but got %v", pkg.Files)
+	}
+	if fakeImportsGroomer.RemoveUnusedImportFromPackageCallCount() != 1 {
+		t.Error("Assertion failed! Expected unused imports to be removed from the file")
+	}
+}
+
+func TestApplyCmdReturnsErrorOnMissingTarget(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+	cmd.SetArgs([]string{"./missing/..."})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error to have occured!")
+	}
+	if fakeInstrumenter.InstrumentDirectoryCallCount() != 0 {
+		t.Error("Assertion failed! Expected no instrumentation with missing target")
+	}
+}
+
+func TestApplyCmdDryRun(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	fakeFileStore.ChangesReturns([]tracing.FileChange{
+		{Path: "main.go", Original: []byte("package main\n\nfunc main() {\n}\n"), Content: []byte("package main\n\nfunc main() {\n\n\t/* prinTracer */\n\tdefer prt.Enter()() /* prinTracer */\n\n}\n")},
+	}, nil)
+	applyCmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore)
+	var buff bytes.Buffer
+	applyCmd.out = &buff
+	cmd := applyCmd.Prepare()
+	cmd.SetArgs([]string{"--dry-run"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fakeFileStore.CommitCallCount() != 0 || fakeFileStore.DiscardCallCount() != 1 {
+		t.Error("Assertion failed! Expected changes to be discarded instead of committed")
+	}
+	expected := "--- a/main.go\n+++ b/main.go\n@@ -1,4 +1,8 @@\n package main\n \n func main() {\n+\n+\t/* prinTracer */\n+\tdefer prt.Enter()() /* prinTracer */\n+\n }\n\nSummary:\nmain.go: would instrument main\n"
+	if buff.String() != expected {
+		t.Errorf("Assertion failed! Expected %s got %s", expected, buff.String())
+	}
+}
+
+func TestApplyCmdCommitsChanges(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if fakeFileStore.CommitCallCount() != 1 {
+		t.Error("Assertion failed! Expected changes to be committed")
+	}
+}
+
+func TestApplyCmdDiscardsChangesOnError(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+	fakeInstrumenter.InstrumentDirectoryReturns(errors.New("error"))
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error to have occured!")
+	}
+	if fakeFileStore.CommitCallCount() != 0 || fakeFileStore.DiscardCallCount() != 1 {
+		t.Error("Assertion failed! Expected changes to be discarded")
+	}
+}
+
+func TestApplyCmdOverlay(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	fakeFileStore.WriteOverlayReturns("/tmp/overlay/overlay.json", nil)
+	applyCmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore)
+	var buff bytes.Buffer
+	applyCmd.out = &buff
+	cmd := applyCmd.Prepare()
+	cmd.SetArgs([]string{"--overlay", "--overlay-dir", "/tmp/overlay"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fakeFileStore.CommitCallCount() != 0 || fakeFileStore.WriteOverlayCallCount() != 1 {
+		t.Fatal("Assertion failed! Expected changes to be written to the overlay instead of committed")
+	}
+	if dir := fakeFileStore.WriteOverlayArgsForCall(0); dir != "/tmp/overlay" {
+		t.Errorf("Assertion failed! Expected overlay directory /tmp/overlay but got %s", dir)
+	}
+	if !strings.Contains(buff.String(), "go build -overlay /tmp/overlay/overlay.json") {
+		t.Errorf("Assertion failed! Expected usage of the overlay but got %s", buff.String())
+	}
+}
+
+func TestApplyCmdReturnsErrorOnDryRunWithOverlay(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewApplyCmd(fakeInstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+	cmd.SetArgs([]string{"--overlay", "--dry-run"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error to have occured!")
+	}
+	if fakeInstrumenter.InstrumentDirectoryCallCount() != 0 {
+		t.Error("Assertion failed! Expected no instrumentation")
+	}
+}
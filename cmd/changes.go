@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/DimitarPetrov/printracer/tracing"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// finishChanges commits the changes made in the store, or in dry run mode prints them and discards them.
+// Changes are discarded if the operation making them failed with err.
+func finishChanges(store tracing.FileStore, err error, dryRun bool, out io.Writer) error {
+	if err != nil {
+		store.Discard()
+		return err
+	}
+	if !dryRun {
+		return store.Commit()
+	}
+	defer store.Discard()
+
+	changes, err := store.Changes()
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting current working directory: %v", err)
+	}
+	return printChanges(out, wd, changes)
+}
+
+// printChanges prints a unified diff of every change followed by a summary of the functions touched by them.
+func printChanges(out io.Writer, wd string, changes []tracing.FileChange) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(out, "No changes.")
+		return err
+	}
+
+	var summary strings.Builder
+	for _, change := range changes {
+		path := displayPath(wd, change.Path)
+		fromFile, toFile := "a/"+path, "b/"+path
+		if change.Original == nil {
+			fromFile = "/dev/null"
+		}
+		if change.Content == nil {
+			toFile = "/dev/null"
+		}
+		if _, err := io.WriteString(out, unifiedDiff(fromFile, toFile, change.Original, change.Content)); err != nil {
+			return err
+		}
+
+		instrumented, reverted, err := tracing.ChangedFunctions(change)
+		if err != nil {
+			return err
+		}
+		var actions []string
+		if len(instrumented) > 0 {
+			actions = append(actions, fmt.Sprintf("instrument %s", strings.Join(instrumented, ", ")))
+		}
+		if len(reverted) > 0 {
+			actions = append(actions, fmt.Sprintf("revert %s", strings.Join(reverted, ", ")))
+		}
+		switch {
+		case change.Original == nil:
+			actions = append(actions, "create the file")
+		case change.Content == nil:
+			actions = append(actions, "remove the file")
+		case len(actions) == 0:
+			actions = append(actions, "modify the file")
+		}
+		fmt.Fprintf(&summary, "%s: would %s\n", path, strings.Join(actions, " and "))
+	}
+
+	_, err := fmt.Fprintf(out, "\nSummary:\n%s", summary.String())
+	return err
+}
+
+// displayPath returns the path relative to the working directory if it is below it.
+func displayPath(wd, path string) string {
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// defaultOverlayDir returns the overlay directory of the working directory in the user cache directory.
+func defaultOverlayDir(wd string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting user cache directory: %v", err)
+	}
+	sum := sha256.Sum256([]byte(wd))
+	return filepath.Join(cacheDir, "printracer", "overlay", fmt.Sprintf("%x", sum[:8])), nil
+}
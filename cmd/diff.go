@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type diffOp struct {
+	kind byte // ' ' for lines present in both, '-' for removed and '+' for added lines
+	line string
+}
+
+// unifiedDiff returns the unified diff between a and b or an empty string if they are equal.
+func unifiedDiff(fromFile, toFile string, a, b []byte) string {
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var buff bytes.Buffer
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk while the changes are close enough to share context.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				if i-last > 2*diffContextLines {
+					break
+				}
+				last = i
+			}
+		}
+		hunkStart, hunkEnd := maxInt(first-diffContextLines, start), minInt(last+diffContextLines+1, len(ops))
+
+		if buff.Len() == 0 {
+			fmt.Fprintf(&buff, "--- %s\n+++ %s\n", fromFile, toFile)
+		}
+		aStart, bStart := lineNumbers(ops[:hunkStart])
+		aLen, bLen := lineNumbers(ops[hunkStart:hunkEnd])
+		fmt.Fprintf(&buff, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+		for _, op := range ops[hunkStart:hunkEnd] {
+			buff.WriteByte(op.kind)
+			buff.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				buff.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = hunkEnd
+	}
+	return buff.String()
+}
+
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// lineNumbers returns the count of lines of a and b covered by the operations.
+func lineNumbers(ops []diffOp) (int, int) {
+	a, b := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			a++
+		}
+		if op.kind != '-' {
+			b++
+		}
+	}
+	return a, b
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes the shortest edit script turning a into b using the Myers algorithm.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int // Snapshots of v for the diagonals -d..d before every step d
+
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string) []diffOp {
+	var reversed []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		snapshot := trace[d]
+		at := func(k int) int { return snapshot[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, diffOp{kind: ' ', line: a[x]})
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, diffOp{kind: '+', line: b[y]})
+		} else {
+			x--
+			reversed = append(reversed, diffOp{kind: '-', line: a[x]})
+		}
+	}
+	for x > 0 {
+		x--
+		reversed = append(reversed, diffOp{kind: ' ', line: a[x]})
+	}
+
+	ops := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		Name     string
+		A        string
+		B        string
+		Expected string
+	}{
+		{Name: "Equal", A: "a\nb\n", B: "a\nb\n", Expected: ""},
+		{Name: "Created", A: "", B: "a\nb\n", Expected: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{Name: "Removed", A: "a\nb\n", B: "", Expected: "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-a\n-b\n"},
+		{
+			Name:     "SingleHunk",
+			A:        "1\n2\n3\n4\n5\n",
+			B:        "1\n2\nthree\n4\n5\n",
+			Expected: "--- a\n+++ b\n@@ -1,5 +1,5 @@\n 1\n 2\n-3\n+three\n 4\n 5\n",
+		},
+		{
+			Name:     "MultipleHunks",
+			A:        "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			B:        "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			Expected: "--- a\n+++ b\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -7,4 +8,3 @@\n 7\n 8\n 9\n-10\n",
+		},
+		{Name: "NoNewLineAtEndOfFile", A: "a\n", B: "a\nb", Expected: "--- a\n+++ b\n@@ -1 +1,2 @@\n a\n+b\n\\ No newline at end of file\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if diff := unifiedDiff("a", "b", []byte(test.A), []byte(test.B)); diff != test.Expected {
+				t.Errorf("Assertion failed! Expected %q got %q", test.Expected, diff)
+			}
+		})
+	}
+}
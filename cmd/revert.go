@@ -4,43 +4,75 @@ import (
 	"fmt"
 	"github.com/DimitarPetrov/printracer/tracing"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
 )
 
 type RevertCmd struct {
 	deinstrumenter tracing.CodeDeinstrumenter
 	importsGroomer tracing.ImportsGroomer
+	store          tracing.FileStore
+	out            io.Writer
+
+	dryRun  bool
+	targets []target
 }
 
-func NewRevertCmd(deinstrumenter tracing.CodeDeinstrumenter, importsGroomer tracing.ImportsGroomer) *RevertCmd {
+func NewRevertCmd(deinstrumenter tracing.CodeDeinstrumenter, importsGroomer tracing.ImportsGroomer, store tracing.FileStore) *RevertCmd {
 	return &RevertCmd{
 		deinstrumenter: deinstrumenter,
 		importsGroomer: importsGroomer,
+		store:          store,
+		out:            os.Stdout,
 	}
 }
 
 func (rc *RevertCmd) Prepare() *cobra.Command {
-	return &cobra.Command{
-		Use:          "revert",
-		Aliases:      []string{"r"},
-		Short:        "Reverts previously instrumented directory of go files",
+	result := &cobra.Command{
+		Use:     "revert [directories | files | packages]",
+		Aliases: []string{"r"},
+		Short:   "Reverts previously instrumented directory of go files",
+		Long: `Reverts previously instrumented go files. Arguments can be directories, go files, import paths of packages in
+the current module and patterns like ./internal/... which include all the subdirectories.
+Without arguments the current directory and all of its subdirectories are reverted.`,
 		PreRunE:      commonPreRunE(rc),
 		RunE:         commonRunE(rc),
 		SilenceUsage: true,
 	}
+
+	result.Flags().BoolVar(&rc.dryRun, "dry-run", false, "print a unified diff of every file and a summary of the functions which would be reverted without writing anything")
+	return result
 }
 
-func (rc *RevertCmd) Run() error {
+func (rc *RevertCmd) Validate(args []string) error {
 	wd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("error getting current working directory: %v", err)
 	}
+	rc.targets, err = resolveTargets(wd, args)
+	return err
+}
 
-	return mapDirectory(wd, func(path string) error {
+func (rc *RevertCmd) Run() error {
+	importsToRemove := tracing.InstrumentationImports()
+	err := mapTargets(rc.targets, func(path string) error {
 		err := rc.deinstrumenter.DeinstrumentDirectory(path)
 		if err != nil {
 			return err
 		}
-		return rc.importsGroomer.RemoveUnusedImportFromDirectory(path, map[string]string{"fmt": "", "runtime": "rt", "crypto/rand": ""}) // TODO: flag for import aliases
+		return rc.importsGroomer.RemoveUnusedImportFromDirectory(path, importsToRemove)
+	}, func(path string) error {
+		fset, pkg, err := parseFilePackage(rc.store, path)
+		if err != nil {
+			return err
+		}
+		if err := rc.deinstrumenter.DeinstrumentPackage(fset, pkg); err != nil {
+			return err
+		}
+		if fset, pkg, err = parseFilePackage(rc.store, path); err != nil {
+			return err
+		}
+		return rc.importsGroomer.RemoveUnusedImportFromPackage(fset, pkg, importsToRemove)
 	})
+	return finishChanges(rc.store, err, rc.dryRun, rc.out)
 }
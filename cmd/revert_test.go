@@ -1,15 +1,19 @@
 package cmd
 
 import (
+	"bytes"
 	"errors"
+	"github.com/DimitarPetrov/printracer/tracing"
 	"github.com/DimitarPetrov/printracer/tracing/tracingfakes"
+	"io/ioutil"
 	"testing"
 )
 
 func TestRevertCmd(t *testing.T) {
 	fakeDeinstrumenter := &tracingfakes.FakeCodeDeinstrumenter{}
 	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
-	cmd := NewRevertCmd(fakeDeinstrumenter, fakeImportsGroomer).Prepare()
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewRevertCmd(fakeDeinstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
 
 	if err := cmd.Execute(); err != nil {
 		t.Fatal(err)
@@ -19,7 +23,8 @@ func TestRevertCmd(t *testing.T) {
 func TestRevertCmdReturnsErrorWhenDeinstrumenterReturnError(t *testing.T) {
 	fakeDeinstrumenter := &tracingfakes.FakeCodeDeinstrumenter{}
 	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
-	cmd := NewRevertCmd(fakeDeinstrumenter, fakeImportsGroomer).Prepare()
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewRevertCmd(fakeDeinstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
 
 	expectedErr := errors.New("error")
 	fakeDeinstrumenter.DeinstrumentDirectoryReturns(expectedErr)
@@ -32,7 +37,8 @@ func TestRevertCmdReturnsErrorWhenDeinstrumenterReturnError(t *testing.T) {
 func TestRevertCmdReturnsErrorWhenImportsGroomerReturnError(t *testing.T) {
 	fakeDeinstrumenter := &tracingfakes.FakeCodeDeinstrumenter{}
 	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
-	cmd := NewRevertCmd(fakeDeinstrumenter, fakeImportsGroomer).Prepare()
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewRevertCmd(fakeDeinstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
 
 	expectedErr := errors.New("error")
 	fakeImportsGroomer.RemoveUnusedImportFromDirectoryReturns(expectedErr)
@@ -41,3 +47,49 @@ func TestRevertCmdReturnsErrorWhenImportsGroomerReturnError(t *testing.T) {
 		t.Error("Assertion failed!")
 	}
 }
+
+func TestRevertCmdRevertsOnlyTargets(t *testing.T) {
+	fakeDeinstrumenter := &tracingfakes.FakeCodeDeinstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewRevertCmd(fakeDeinstrumenter, fakeImportsGroomer, fakeFileStore).Prepare()
+	fakeFileStore.ReadFileStub = ioutil.ReadFile
+	cmd.SetArgs([]string{"revert.go"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fakeDeinstrumenter.DeinstrumentDirectoryCallCount() != 0 {
+		t.Error("Assertion failed! Expected no directory to be reverted")
+	}
+	if fakeDeinstrumenter.DeinstrumentPackageCallCount() != 1 || fakeImportsGroomer.RemoveUnusedImportFromPackageCallCount() != 1 {
+		t.Error("Assertion failed! Expected a single file to be reverted")
+	}
+}
+
+func TestRevertCmdDryRun(t *testing.T) {
+	fakeDeinstrumenter := &tracingfakes.FakeCodeDeinstrumenter{}
+	fakeImportsGroomer := &tracingfakes.FakeImportsGroomer{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	fakeFileStore.ChangesReturns([]tracing.FileChange{
+		{Path: "printracer_config.go", Original: []byte("// Code generated by printracer. DO NOT EDIT.\n\npackage main\n")},
+	}, nil)
+	revertCmd := NewRevertCmd(fakeDeinstrumenter, fakeImportsGroomer, fakeFileStore)
+	var buff bytes.Buffer
+	revertCmd.out = &buff
+	cmd := revertCmd.Prepare()
+	cmd.SetArgs([]string{"--dry-run"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fakeFileStore.CommitCallCount() != 0 {
+		t.Error("Assertion failed! Expected changes not to be committed")
+	}
+	expected := "--- a/printracer_config.go\n+++ /dev/null\n@@ -1,3 +0,0 @@\n-// Code generated by printracer. DO NOT EDIT.\n-\n-package main\n\nSummary:\nprintracer_config.go: would remove the file\n"
+	if buff.String() != expected {
+		t.Errorf("Assertion failed! Expected %s got %s", expected, buff.String())
+	}
+}
@@ -8,19 +8,24 @@ import (
 )
 
 type RootCmd struct {
+	store          tracing.FileStore
 	instrumenter   tracing.CodeInstrumenter
 	deinstrumenter tracing.CodeDeinstrumenter
 	importsGroomer tracing.ImportsGroomer
 	parser         parser.Parser
+	jsonParser     parser.Parser
 	visualizer     vis.Visualizer
 }
 
 func NewRootCmd() *RootCmd {
+	store := tracing.NewFileStore()
 	return &RootCmd{
-		instrumenter:   tracing.NewCodeInstrumenter(),
-		deinstrumenter: tracing.NewCodeDeinstrumenter(),
-		importsGroomer: tracing.NewImportsGroomer(),
+		store:          store,
+		instrumenter:   tracing.NewCodeInstrumenter(store),
+		deinstrumenter: tracing.NewCodeDeinstrumenter(store),
+		importsGroomer: tracing.NewImportsGroomer(store),
 		parser:         parser.NewParser(),
+		jsonParser:     parser.NewJSONParser(),
 		visualizer:     vis.NewVisualizer(),
 	}
 }
@@ -32,9 +37,11 @@ func (rc *RootCmd) Prepare() *cobra.Command {
 		Long:  `printracer instruments every go file in the current working directory to print every function execution along with its arguments.`,
 	}
 
-	rootCmd.AddCommand(NewApplyCmd(rc.instrumenter, rc.importsGroomer).Prepare())
-	rootCmd.AddCommand(NewRevertCmd(rc.deinstrumenter, rc.importsGroomer).Prepare())
-	rootCmd.AddCommand(NewVisualizeCmd(rc.parser, rc.visualizer).Prepare())
+	rootCmd.AddCommand(NewApplyCmd(rc.instrumenter, rc.importsGroomer, rc.store).Prepare())
+	rootCmd.AddCommand(NewRevertCmd(rc.deinstrumenter, rc.importsGroomer, rc.store).Prepare())
+	rootCmd.AddCommand(NewVisualizeCmd(rc.parser, rc.jsonParser, rc.visualizer).Prepare())
+	rootCmd.AddCommand(NewTestCmd(rc.instrumenter, rc.importsGroomer, rc.store).Prepare())
+	rootCmd.AddCommand(NewRunCmd(rc.instrumenter, rc.importsGroomer, rc.store, NewVisualizeCmd(rc.parser, rc.jsonParser, rc.visualizer)).Prepare())
 
 	return rootCmd
 }
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/DimitarPetrov/printracer/rt"
+	"github.com/DimitarPetrov/printracer/tracing"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+)
+
+type RunCmd struct {
+	instrumenter   tracing.CodeInstrumenter
+	importsGroomer tracing.ImportsGroomer
+	store          tracing.FileStore
+	visualizeCmd   *VisualizeCmd
+
+	options    tracing.Options
+	instrument []string
+	traceFile  string
+	visualize  bool
+
+	targets []target
+	pkg     string
+	args    []string
+}
+
+func NewRunCmd(instrumenter tracing.CodeInstrumenter, importsGroomer tracing.ImportsGroomer, store tracing.FileStore, visualizeCmd *VisualizeCmd) *RunCmd {
+	return &RunCmd{
+		instrumenter:   instrumenter,
+		importsGroomer: importsGroomer,
+		store:          store,
+		visualizeCmd:   visualizeCmd,
+	}
+}
+
+func (rc *RunCmd) Prepare() *cobra.Command {
+	result := &cobra.Command{
+		Use:   "run [flags] [--] package [arguments...]",
+		Short: "Instruments, builds and runs a go program writing its trace to a file without modifying the working tree",
+		Long: `Instruments the go files in an overlay without modifying the working tree, builds the package with go build -overlay
+and runs it with the given arguments. The trace is written to a file instead of the standard output of the program,
+which can be visualized afterwards with --visualize.
+Flags after the package are passed to the program.`,
+		PreRunE:      commonPreRunE(rc),
+		RunE:         commonRunE(rc),
+		SilenceUsage: true,
+	}
+
+	result.Flags().SetInterspersed(false)
+	addInstrumentationFlags(result.Flags(), &rc.options)
+	result.Flags().StringArrayVar(&rc.instrument, "instrument", nil, "directories, go files, import paths or patterns like ./internal/... to be instrumented. Can be repeated. Defaults to the current directory and all of its subdirectories.")
+	result.Flags().StringVarP(&rc.traceFile, "trace", "t", "trace.txt", "file the trace is written to")
+	result.Flags().BoolVar(&rc.visualize, "visualize", false, "visualize the trace after the program exits")
+	result.Flags().StringVarP(&rc.visualizeCmd.outputFile, "output", "o", "calls", "name of the resulting html file when visualizing")
+	return result
+}
+
+func (rc *RunCmd) Validate(args []string) error {
+	if err := validateInstrumentationOptions(rc.options); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("missing package to run")
+	}
+	rc.pkg, rc.args = args[0], args[1:]
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting current working directory: %v", err)
+	}
+	rc.targets, err = resolveTargets(wd, rc.instrument)
+	return err
+}
+
+func (rc *RunCmd) Run() error {
+	if err := instrumentTargets(rc.instrumenter, rc.importsGroomer, rc.store, rc.targets, rc.options); err != nil {
+		rc.store.Discard()
+		return err
+	}
+
+	tempDir, err := ioutil.TempDir("", "printracer-run")
+	if err != nil {
+		return fmt.Errorf("failed creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	overlayFile, err := rc.store.WriteOverlay(filepath.Join(tempDir, "overlay"))
+	if err != nil {
+		return err
+	}
+	binary := filepath.Join(tempDir, "program")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	build := exec.Command("go", "build", "-overlay", overlayFile, "-o", binary, rc.pkg)
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("failed building %s: %v", rc.pkg, err)
+	}
+
+	traceFile, err := filepath.Abs(rc.traceFile)
+	if err != nil {
+		return fmt.Errorf("failed resolving absolute path of %s: %v", rc.traceFile, err)
+	}
+	if err := ioutil.WriteFile(traceFile, nil, 0664); err != nil { // The trace is appended to the file
+		return fmt.Errorf("failed truncating trace file %s: %v", traceFile, err)
+	}
+	runErr := rc.runProgram(binary, traceFile)
+
+	if rc.visualize {
+		if err := rc.visualizeTrace(traceFile); err != nil {
+			return err
+		}
+	}
+	return runErr
+}
+
+// runProgram runs the program with the trace redirected to the trace file, so that it is not mixed with its output.
+func (rc *RunCmd) runProgram(binary, traceFile string) error {
+	program := exec.Command(binary, rc.args...)
+	program.Stdin, program.Stdout, program.Stderr = os.Stdin, os.Stdout, os.Stderr
+	program.Env = append(os.Environ(), rt.OutputEnv+"="+traceFile)
+
+	// Interrupts are handled by the program, while printracer waits for it in order to handle its trace.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	if err := program.Run(); err != nil {
+		return fmt.Errorf("program %s failed: %v", rc.pkg, err)
+	}
+	return nil
+}
+
+func (rc *RunCmd) visualizeTrace(traceFile string) error {
+	f, err := os.Open(traceFile)
+	if err != nil {
+		return fmt.Errorf("error opening input file %s: %v", traceFile, err)
+	}
+	defer f.Close()
+
+	rc.visualizeCmd.input = f
+	rc.visualizeCmd.maxDepth = math.MaxInt32
+	return rc.visualizeCmd.Run()
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/DimitarPetrov/printracer/parser/parserfakes"
+	"github.com/DimitarPetrov/printracer/tracing/tracingfakes"
+	"github.com/DimitarPetrov/printracer/vis/visfakes"
+	"reflect"
+	"testing"
+)
+
+func TestRunCmdPassesFlagsAfterPackageToProgram(t *testing.T) {
+	runCmd := NewRunCmd(&tracingfakes.FakeCodeInstrumenter{}, &tracingfakes.FakeImportsGroomer{}, &tracingfakes.FakeFileStore{},
+		NewVisualizeCmd(&parserfakes.FakeParser{}, &parserfakes.FakeParser{}, &visfakes.FakeVisualizer{}))
+	cmd := runCmd.Prepare()
+
+	tests := []struct {
+		Args         []string
+		ExpectedPkg  string
+		ExpectedArgs []string
+	}{
+		{Args: []string{"--format", "json", "./cmd/server", "--port", "8080"}, ExpectedPkg: "./cmd/server", ExpectedArgs: []string{"--port", "8080"}},
+		{Args: []string{"--", "./cmd/server", "-v"}, ExpectedPkg: "./cmd/server", ExpectedArgs: []string{"-v"}},
+		{Args: []string{"."}, ExpectedPkg: ".", ExpectedArgs: []string{}},
+	}
+
+	for _, test := range tests {
+		if err := cmd.ParseFlags(test.Args); err != nil {
+			t.Fatal(err)
+		}
+		if err := runCmd.Validate(cmd.Flags().Args()); err != nil {
+			t.Fatal(err)
+		}
+		if runCmd.pkg != test.ExpectedPkg || !reflect.DeepEqual(runCmd.args, test.ExpectedArgs) {
+			t.Errorf("Assertion failed! Expected package %s with args %v but got %s with %v", test.ExpectedPkg, test.ExpectedArgs, runCmd.pkg, runCmd.args)
+		}
+	}
+}
+
+func TestRunCmdReturnsErrorWithoutPackage(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	cmd := NewRunCmd(fakeInstrumenter, &tracingfakes.FakeImportsGroomer{}, &tracingfakes.FakeFileStore{},
+		NewVisualizeCmd(&parserfakes.FakeParser{}, &parserfakes.FakeParser{}, &visfakes.FakeVisualizer{})).Prepare()
+	cmd.SetArgs([]string{"--format", "json"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error to have occured!")
+	}
+	if fakeInstrumenter.InstrumentDirectoryCallCount() != 0 {
+		t.Error("Assertion failed! Expected no instrumentation without package")
+	}
+}
+
+func TestRunCmdReturnsErrorWhenInstrumenterReturnError(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewRunCmd(fakeInstrumenter, &tracingfakes.FakeImportsGroomer{}, fakeFileStore,
+		NewVisualizeCmd(&parserfakes.FakeParser{}, &parserfakes.FakeParser{}, &visfakes.FakeVisualizer{})).Prepare()
+	cmd.SetArgs([]string{"."})
+
+	expectedErr := errors.New("error")
+	fakeInstrumenter.InstrumentDirectoryReturns(expectedErr)
+
+	if err := cmd.Execute(); err != expectedErr {
+		t.Error("Assertion failed!")
+	}
+	if fakeFileStore.WriteOverlayCallCount() != 0 || fakeFileStore.DiscardCallCount() != 1 {
+		t.Error("Assertion failed! Expected changes to be discarded without writing an overlay")
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/DimitarPetrov/printracer/tracing"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const recursiveSuffix = "..."
+
+// target is a directory or a single go file apply and revert operate on.
+type target struct {
+	path      string
+	isFile    bool
+	recursive bool // Whether the subdirectories of a directory are included as well.
+}
+
+// resolveTargets resolves the positional arguments of apply and revert relative to the working directory wd.
+// Arguments can be directories, go files, import paths of packages in the module of wd, and patterns like ./internal/...
+// which include all the subdirectories. Without arguments the working directory and all of its subdirectories are targeted.
+func resolveTargets(wd string, args []string) ([]target, error) {
+	if len(args) == 0 {
+		return []target{{path: wd, recursive: true}}, nil
+	}
+
+	var targets []target
+	for _, arg := range args {
+		t, err := resolveTarget(wd, arg)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+func resolveTarget(wd string, arg string) (target, error) {
+	pattern := filepath.ToSlash(arg)
+	recursive := pattern == recursiveSuffix || strings.HasSuffix(pattern, "/"+recursiveSuffix)
+	if recursive {
+		pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, recursiveSuffix), "/")
+		if len(pattern) == 0 {
+			pattern = "."
+		}
+	}
+
+	path := filepath.FromSlash(pattern)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(wd, path)
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) && !isLocalPattern(pattern) {
+		path, err = resolveImportPath(wd, pattern)
+		if err != nil {
+			return target{}, err
+		}
+		info, err = os.Stat(path)
+	}
+	if err != nil {
+		return target{}, fmt.Errorf("failed resolving %s: %v", arg, err)
+	}
+
+	if !info.IsDir() {
+		if recursive || filepath.Ext(path) != ".go" {
+			return target{}, fmt.Errorf("failed resolving %s: not a directory or a go file", arg)
+		}
+		return target{path: path, isFile: true}, nil
+	}
+	return target{path: path, recursive: recursive}, nil
+}
+
+// isLocalPattern reports whether the pattern is a file system path as opposed to an import path, following the go command.
+func isLocalPattern(pattern string) bool {
+	return pattern == "." || pattern == ".." || strings.HasPrefix(pattern, "./") || strings.HasPrefix(pattern, "../") || filepath.IsAbs(filepath.FromSlash(pattern))
+}
+
+// resolveImportPath returns the directory of the package with the given import path in the module containing wd.
+func resolveImportPath(wd string, importPath string) (string, error) {
+	root, modulePath, err := tracing.FindModule(wd)
+	if err != nil {
+		return "", fmt.Errorf("failed resolving import path %s: %v", importPath, err)
+	}
+	if importPath == modulePath {
+		return root, nil
+	}
+	if !strings.HasPrefix(importPath, modulePath+"/") {
+		return "", fmt.Errorf("failed resolving import path %s: not in module %s", importPath, modulePath)
+	}
+	return filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(importPath, modulePath+"/"))), nil
+}
+
+// mapTargets applies dirOperation on every targeted directory and fileOperation on every targeted file.
+func mapTargets(targets []target, dirOperation func(string) error, fileOperation func(string) error) error {
+	for _, t := range targets {
+		var err error
+		switch {
+		case t.isFile:
+			err = fileOperation(t.path)
+		case t.recursive:
+			err = mapDirectory(t.path, dirOperation)
+		default:
+			err = dirOperation(t.path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFilePackage parses a single go file from the store as a package of its own, so that it can be processed like a directory.
+func parseFilePackage(store tracing.FileStore, path string) (*token.FileSet, *ast.Package, error) {
+	src, err := store.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed reading go file %s: %v", path, err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed parsing go file %s: %v", path, err)
+	}
+	return fset, &ast.Package{Name: file.Name.Name, Files: map[string]*ast.File{path: file}}, nil
+}
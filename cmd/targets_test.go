@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveTargets(t *testing.T) {
+	root, err := ioutil.TempDir("", "printracer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(root); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err := os.MkdirAll(filepath.Join(root, "internal", "a"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod":               "module example.com/m\n",
+		"internal/a/a.go":      "package a\n",
+		"internal/a/a_test.go": "package a\n",
+		"internal/a/README.md": "",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd := filepath.Join(root, "internal")
+	a := filepath.Join(root, "internal", "a")
+
+	tests := []struct {
+		Name     string
+		Args     []string
+		Expected []target
+	}{
+		{Name: "NoArgs", Args: nil, Expected: []target{{path: wd, recursive: true}}},
+		{Name: "Directory", Args: []string{"a"}, Expected: []target{{path: a}}},
+		{Name: "CurrentDirectory", Args: []string{"."}, Expected: []target{{path: wd}}},
+		{Name: "RecursivePattern", Args: []string{"./..."}, Expected: []target{{path: wd, recursive: true}}},
+		{Name: "RelativeRecursivePattern", Args: []string{"../internal/..."}, Expected: []target{{path: wd, recursive: true}}},
+		{Name: "File", Args: []string{"a/a.go"}, Expected: []target{{path: filepath.Join(a, "a.go"), isFile: true}}},
+		{Name: "TestFile", Args: []string{"a/a_test.go"}, Expected: []target{{path: filepath.Join(a, "a_test.go"), isFile: true}}},
+		{Name: "ImportPath", Args: []string{"example.com/m/internal/a"}, Expected: []target{{path: a}}},
+		{Name: "ModulePathPattern", Args: []string{"example.com/m/..."}, Expected: []target{{path: root, recursive: true}}},
+		{Name: "MultipleArgs", Args: []string{"a", "a/a.go"}, Expected: []target{{path: a}, {path: filepath.Join(a, "a.go"), isFile: true}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			targets, err := resolveTargets(wd, test.Args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(targets, test.Expected) {
+				t.Errorf("Assertion failed! Expected %v got %v", test.Expected, targets)
+			}
+		})
+	}
+
+	invalidArgs := []string{"missing", "./missing", "example.com/other/a", "a/README.md", "a/a.go/..."}
+	for _, arg := range invalidArgs {
+		if _, err := resolveTargets(wd, []string{arg}); err == nil {
+			t.Errorf("Expected error to have occured for %s!", arg)
+		}
+	}
+}
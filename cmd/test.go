@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/DimitarPetrov/printracer/rt"
+	"github.com/DimitarPetrov/printracer/tracing"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Name of the trace file of calls made outside of any test, e.g. in init functions and TestMain.
+const outsideTestsTraceName = "TestMain"
+
+type TestCmd struct {
+	instrumenter   tracing.CodeInstrumenter
+	importsGroomer tracing.ImportsGroomer
+	store          tracing.FileStore
+	out            io.Writer
+
+	options    tracing.Options
+	instrument []string
+	traceDir   string
+
+	targets  []target
+	packages []string
+	testArgs []string
+}
+
+func NewTestCmd(instrumenter tracing.CodeInstrumenter, importsGroomer tracing.ImportsGroomer, store tracing.FileStore) *TestCmd {
+	return &TestCmd{
+		instrumenter:   instrumenter,
+		importsGroomer: importsGroomer,
+		store:          store,
+		out:            os.Stdout,
+	}
+}
+
+func (tc *TestCmd) Prepare() *cobra.Command {
+	result := &cobra.Command{
+		Use:   "test [flags] [--] [packages] [go test flags]",
+		Short: "Instruments and tests go packages writing the trace of every test to a separate file without modifying the working tree",
+		Long: `Instruments the go files in an overlay without modifying the working tree and runs go test -overlay on the packages.
+The trace is split per test (t.Name()) into separate files in the trace directory, which can be visualized on their own.
+Flags after the packages are passed to go test, e.g. printracer test ./pkg -run TestX`,
+		PreRunE:      commonPreRunE(tc),
+		RunE:         commonRunE(tc),
+		SilenceUsage: true,
+	}
+
+	result.Flags().SetInterspersed(false)
+	addInstrumentationFlags(result.Flags(), &tc.options)
+	result.Flags().StringArrayVar(&tc.instrument, "instrument", nil, "directories, go files, import paths or patterns like ./internal/... to be instrumented. Can be repeated. Defaults to the current directory and all of its subdirectories.")
+	result.Flags().StringVar(&tc.traceDir, "trace-dir", "traces", "directory the traces of the tests are written to")
+	return result
+}
+
+func (tc *TestCmd) Validate(args []string) error {
+	if err := validateInstrumentationOptions(tc.options); err != nil {
+		return err
+	}
+	tc.packages, tc.testArgs = nil, nil
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			tc.testArgs = args[i:]
+			break
+		}
+		tc.packages = append(tc.packages, arg)
+	}
+	if len(tc.packages) == 0 {
+		tc.packages = []string{"."}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting current working directory: %v", err)
+	}
+	tc.targets, err = resolveTargets(wd, tc.instrument)
+	return err
+}
+
+func (tc *TestCmd) Run() error {
+	if err := instrumentTargets(tc.instrumenter, tc.importsGroomer, tc.store, tc.targets, tc.options); err != nil {
+		tc.store.Discard()
+		return err
+	}
+
+	tempDir, err := ioutil.TempDir("", "printracer-test")
+	if err != nil {
+		return fmt.Errorf("failed creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	overlayFile, err := tc.store.WriteOverlay(filepath.Join(tempDir, "overlay"))
+	if err != nil {
+		return err
+	}
+
+	goTest := exec.Command("go", append(append([]string{"test", "-json", "-overlay", overlayFile}, tc.packages...), tc.testArgs...)...)
+	goTest.Env = append(os.Environ(), rt.OutputEnv+"="+rt.StdoutOutput) // The trace is attributed to the tests by go test -json
+	goTest.Stderr = os.Stderr
+	stdout, err := goTest.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed running go test: %v", err)
+	}
+	if err := goTest.Start(); err != nil {
+		return fmt.Errorf("failed running go test: %v", err)
+	}
+	traces, splitErr := splitTestTrace(stdout, tc.out)
+	testErr := goTest.Wait()
+	if splitErr != nil {
+		return splitErr
+	}
+
+	if err := tc.writeTraces(traces); err != nil {
+		return err
+	}
+	if testErr != nil {
+		return fmt.Errorf("go test failed: %v", testErr)
+	}
+	return nil
+}
+
+func (tc *TestCmd) writeTraces(traces []*testTrace) error {
+	packages := make(map[string]bool)
+	for _, trace := range traces {
+		packages[trace.pkg] = true
+	}
+
+	for _, trace := range traces {
+		dir := tc.traceDir
+		if len(packages) > 1 {
+			dir = filepath.Join(dir, filepath.FromSlash(trace.pkg))
+		}
+		if err := os.MkdirAll(dir, 0775); err != nil {
+			return fmt.Errorf("failed creating trace directory %s: %v", dir, err)
+		}
+		name := trace.test
+		if len(name) == 0 {
+			name = outsideTestsTraceName
+		}
+		fileName := filepath.Join(dir, traceFileName(name))
+		if err := ioutil.WriteFile(fileName, []byte(trace.trace.String()), 0664); err != nil {
+			return fmt.Errorf("failed writing trace file %s: %v", fileName, err)
+		}
+		if _, err := fmt.Fprintf(tc.out, "Trace of %s written to %s\n", name, fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// traceFileName returns the name of the trace file of the test, e.g. TestX__sub.txt for TestX/sub.
+func traceFileName(test string) string {
+	return strings.NewReplacer("/", "__", `\`, "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_").Replace(test) + ".txt"
+}
+
+// testEvent is an event of go test -json. See go doc test2json.
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+type testTrace struct {
+	pkg   string
+	test  string
+	trace strings.Builder
+
+	pending string // Output of the test not terminated by a new line yet
+}
+
+// splitTestTrace splits the trace in the output of go test -json per test. The rest of the output is written to out.
+// The traces are returned in the order the tests started tracing.
+func splitTestTrace(r io.Reader, out io.Writer) ([]*testTrace, error) {
+	var traces []*testTrace
+	byTest := make(map[[2]string]*testTrace)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event testEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil { // Not an event, e.g. build errors
+			if _, err := fmt.Fprintln(out, scanner.Text()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if event.Action != "output" {
+			continue
+		}
+
+		key := [2]string{event.Package, event.Test}
+		trace, ok := byTest[key]
+		if !ok {
+			trace = &testTrace{pkg: event.Package, test: event.Test}
+			byTest[key] = trace
+		}
+		output := trace.pending + event.Output
+		trace.pending = ""
+		if !strings.HasSuffix(output, "\n") {
+			trace.pending = output
+			continue
+		}
+
+		if isTraceLine(output) {
+			if trace.trace.Len() == 0 {
+				traces = append(traces, trace)
+			}
+			trace.trace.WriteString(output)
+			continue
+		}
+		if _, err := io.WriteString(out, output); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading go test output: %v", err)
+	}
+	return traces, nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"github.com/DimitarPetrov/printracer/tracing/tracingfakes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const goTestJSONOutput = `{"Action":"run","Package":"example.com/m","Test":"TestA"}
+{"Action":"output","Package":"example.com/m","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"output","Package":"example.com/m","Test":"TestA","Output":"Entering function example.com/m.foo called by example.com/m.TestA; callID=1; goroutine=6\n"}
+{"Action":"output","Package":"example.com/m","Test":"TestA/sub","Output":"Entering function example.com/m.bar "}
+{"Action":"output","Package":"example.com/m","Test":"TestA/sub","Output":"called by example.com/m.TestA.func1; callID=2; goroutine=7\n"}
+{"Action":"output","Package":"example.com/m","Test":"TestA","Output":"Exiting function example.com/m.foo called by example.com/m.TestA; callID=1; goroutine=6\n"}
+{"Action":"output","Package":"example.com/m","Test":"TestA","Output":"--- PASS: TestA (0.00s)\n"}
+{"Action":"output","Package":"example.com/m","Output":"{\"event\":\"enter\",\"func\":\"example.com/m.init\"}\n"}
+{"Action":"output","Package":"example.com/m","Output":"ok  \texample.com/m\t0.003s\n"}
+FAIL	example.com/m [build failed]
+`
+
+func TestSplitTestTrace(t *testing.T) {
+	var out bytes.Buffer
+	traces, err := splitTestTrace(strings.NewReader(goTestJSONOutput), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		Test  string
+		Trace string
+	}{
+		{Test: "TestA", Trace: "Entering function example.com/m.foo called by example.com/m.TestA; callID=1; goroutine=6\nExiting function example.com/m.foo called by example.com/m.TestA; callID=1; goroutine=6\n"},
+		{Test: "TestA/sub", Trace: "Entering function example.com/m.bar called by example.com/m.TestA.func1; callID=2; goroutine=7\n"},
+		{Test: "", Trace: "{\"event\":\"enter\",\"func\":\"example.com/m.init\"}\n"},
+	}
+	if len(traces) != len(expected) {
+		t.Fatalf("Assertion failed! Expected %d traces but got %d", len(expected), len(traces))
+	}
+	for i, trace := range traces {
+		if trace.pkg != "example.com/m" || trace.test != expected[i].Test || trace.trace.String() != expected[i].Trace {
+			t.Errorf("Assertion failed! Unexpected trace of %s: %s", trace.test, trace.trace.String())
+		}
+	}
+
+	expectedOut := "=== RUN   TestA\n--- PASS: TestA (0.00s)\nok  \texample.com/m\t0.003s\nFAIL\texample.com/m [build failed]\n"
+	if out.String() != expectedOut {
+		t.Errorf("Assertion failed! Expected output %q got %q", expectedOut, out.String())
+	}
+}
+
+func TestTraceFileName(t *testing.T) {
+	if name := traceFileName("TestA/sub_case:1"); name != "TestA__sub_case_1.txt" {
+		t.Errorf("Assertion failed! Unexpected trace file name %s", name)
+	}
+}
+
+func TestTestCmdPassesFlagsAfterPackagesToGoTest(t *testing.T) {
+	testCmd := NewTestCmd(&tracingfakes.FakeCodeInstrumenter{}, &tracingfakes.FakeImportsGroomer{}, &tracingfakes.FakeFileStore{})
+	cmd := testCmd.Prepare()
+
+	tests := []struct {
+		Args             []string
+		ExpectedPackages []string
+		ExpectedTestArgs []string
+	}{
+		{Args: []string{"--include-tests", "./pkg", "-run", "TestX"}, ExpectedPackages: []string{"./pkg"}, ExpectedTestArgs: []string{"-run", "TestX"}},
+		{Args: []string{"./a", "./b/..."}, ExpectedPackages: []string{"./a", "./b/..."}},
+		{Args: []string{"--", "-run", "TestX"}, ExpectedPackages: []string{"."}, ExpectedTestArgs: []string{"-run", "TestX"}},
+	}
+
+	for _, test := range tests {
+		if err := cmd.ParseFlags(test.Args); err != nil {
+			t.Fatal(err)
+		}
+		if err := testCmd.Validate(cmd.Flags().Args()); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(testCmd.packages, test.ExpectedPackages) || !reflect.DeepEqual(testCmd.testArgs, test.ExpectedTestArgs) {
+			t.Errorf("Assertion failed! Expected packages %v with args %v but got %v with %v", test.ExpectedPackages, test.ExpectedTestArgs, testCmd.packages, testCmd.testArgs)
+		}
+	}
+	if !testCmd.options.IncludeTests {
+		t.Error("Assertion failed! Expected test files to be instrumented")
+	}
+}
+
+func TestTestCmdReturnsErrorWhenInstrumenterReturnError(t *testing.T) {
+	fakeInstrumenter := &tracingfakes.FakeCodeInstrumenter{}
+	fakeFileStore := &tracingfakes.FakeFileStore{}
+	cmd := NewTestCmd(fakeInstrumenter, &tracingfakes.FakeImportsGroomer{}, fakeFileStore).Prepare()
+
+	expectedErr := errors.New("error")
+	fakeInstrumenter.InstrumentDirectoryReturns(expectedErr)
+
+	if err := cmd.Execute(); err != expectedErr {
+		t.Error("Assertion failed!")
+	}
+	if fakeFileStore.WriteOverlayCallCount() != 0 || fakeFileStore.DiscardCallCount() != 1 {
+		t.Error("Assertion failed! Expected changes to be discarded without writing an overlay")
+	}
+}
@@ -1,17 +1,22 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"github.com/DimitarPetrov/printracer/parser"
 	"github.com/DimitarPetrov/printracer/vis"
 	"github.com/spf13/cobra"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
+	"strings"
 )
 
 type VisualizeCmd struct {
 	parser     parser.Parser
+	jsonParser parser.Parser
 	visualizer vis.Visualizer
 
 	input io.Reader
@@ -21,9 +26,10 @@ type VisualizeCmd struct {
 	startingFunc string
 }
 
-func NewVisualizeCmd(parser parser.Parser, visualizer vis.Visualizer) *VisualizeCmd {
+func NewVisualizeCmd(parser parser.Parser, jsonParser parser.Parser, visualizer vis.Visualizer) *VisualizeCmd {
 	return &VisualizeCmd{
 		parser:     parser,
+		jsonParser: jsonParser,
 		visualizer: visualizer,
 	}
 }
@@ -39,8 +45,8 @@ func (vc *VisualizeCmd) Prepare() *cobra.Command {
 	}
 
 	result.Flags().StringVarP(&vc.outputFile, "output", "o", "calls", "name of the resulting html file when visualizing")
-	result.Flags().IntVarP(&vc.maxDepth, "depth", "d", math.MaxInt32, "maximum depth in call graph. NOTE: If used visualization will be linear following the call stack of the starting func. Calls from different Goroutines will be ignored!")
-	result.Flags().StringVarP(&vc.startingFunc, "func", "f", "", "name of the starting function in the visualization (the root of the diagram). NOTE: If used visualization will be linear following the call stack of the starting func. Calls from different Goroutines will be ignored!")
+	result.Flags().IntVarP(&vc.maxDepth, "depth", "d", math.MaxInt32, "maximum depth in call graph. NOTE: If used visualization will be linear following the call stack of the starting func on its goroutine.")
+	result.Flags().StringVarP(&vc.startingFunc, "func", "f", "", "name of the starting function in the visualization (the root of the diagram). NOTE: If used visualization will be linear following the call stack of the starting func on its goroutine.")
 	return result
 }
 
@@ -57,7 +63,16 @@ func (vc *VisualizeCmd) Validate(args []string) error {
 }
 
 func (vc *VisualizeCmd) Run() error {
-	events, err := vc.parser.Parse(vc.input)
+	input, err := ioutil.ReadAll(vc.input)
+	if err != nil {
+		return fmt.Errorf("error reading input: %v", err)
+	}
+
+	p := vc.parser
+	if isJSONTrace(input) {
+		p = vc.jsonParser
+	}
+	events, err := p.Parse(bytes.NewReader(input))
 	if err != nil {
 		return fmt.Errorf("error while parsing input: %v", err)
 	}
@@ -66,3 +81,24 @@ func (vc *VisualizeCmd) Run() error {
 	}
 	return nil
 }
+
+// isJSONTrace reports whether the first trace line of the input is in JSON format.
+// Lines which are not part of the trace (e.g. output of the traced program) are skipped.
+func isJSONTrace(input []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, `{"event":`) {
+			return true
+		}
+		if strings.HasPrefix(line, "Entering function") || strings.HasPrefix(line, "Exiting function") {
+			return false
+		}
+	}
+	return false
+}
+
+// isTraceLine reports whether the line is a line of a trace in any format.
+func isTraceLine(line string) bool {
+	return strings.HasPrefix(line, `{"event":`) || strings.HasPrefix(line, "Entering function") || strings.HasPrefix(line, "Exiting function")
+}
@@ -11,7 +11,7 @@ import (
 func TestVisualizeCmd(t *testing.T) {
 	fakeVisualizer := &visfakes.FakeVisualizer{}
 	fakeParser := &parserfakes.FakeParser{}
-	cmd := NewVisualizeCmd(fakeParser, fakeVisualizer).Prepare()
+	cmd := NewVisualizeCmd(fakeParser, &parserfakes.FakeParser{}, fakeVisualizer).Prepare()
 
 	if err := cmd.Execute(); err != nil {
 		t.Fatal(err)
@@ -21,7 +21,7 @@ func TestVisualizeCmd(t *testing.T) {
 func TestVisualizeCmdReturnsErrorWhenParserReturnError(t *testing.T) {
 	fakeVisualizer := &visfakes.FakeVisualizer{}
 	fakeParser := &parserfakes.FakeParser{}
-	cmd := NewVisualizeCmd(fakeParser, fakeVisualizer).Prepare()
+	cmd := NewVisualizeCmd(fakeParser, &parserfakes.FakeParser{}, fakeVisualizer).Prepare()
 
 	expectedErr := errors.New("error")
 	fakeParser.ParseReturns(nil, expectedErr)
@@ -36,7 +36,7 @@ func TestVisualizeCmdReturnsErrorWhenParserReturnError(t *testing.T) {
 func TestVisualizeCmdReturnsErrorWhenVisualizerReturnError(t *testing.T) {
 	fakeVisualizer := &visfakes.FakeVisualizer{}
 	fakeParser := &parserfakes.FakeParser{}
-	cmd := NewVisualizeCmd(fakeParser, fakeVisualizer).Prepare()
+	cmd := NewVisualizeCmd(fakeParser, &parserfakes.FakeParser{}, fakeVisualizer).Prepare()
 
 	expectedErr := errors.New("error")
 	fakeVisualizer.VisualizeReturns(expectedErr)
@@ -51,7 +51,7 @@ func TestVisualizeCmdReturnsErrorWhenVisualizerReturnError(t *testing.T) {
 func TestVisualizeCmdErrorWhileValidatingArgs(t *testing.T) {
 	fakeVisualizer := &visfakes.FakeVisualizer{}
 	fakeParser := &parserfakes.FakeParser{}
-	cmd := NewVisualizeCmd(fakeParser, fakeVisualizer)
+	cmd := NewVisualizeCmd(fakeParser, &parserfakes.FakeParser{}, fakeVisualizer)
 
 	if err := cmd.Validate([]string{"test"}); err == nil {
 		t.Error("Expected error to have occured!")
@@ -59,3 +59,35 @@ func TestVisualizeCmdErrorWhileValidatingArgs(t *testing.T) {
 		t.Error("Assertion failed!")
 	}
 }
+
+func TestVisualizeCmdDetectsTraceFormat(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Input string
+		JSON  bool
+	}{
+		{Name: "Text", Input: "program output\nEntering function main.main called by runtime.main; callID=1d8ca74e-c860-8a75-fc36-fe6d34350f0c\n", JSON: false},
+		{Name: "JSON", Input: "{\"level\":\"info\"}\n{\"event\":\"enter\",\"func\":\"main.main\",\"caller\":\"runtime.main\"}\n", JSON: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fakeParser := &parserfakes.FakeParser{}
+			fakeJSONParser := &parserfakes.FakeParser{}
+			cmd := NewVisualizeCmd(fakeParser, fakeJSONParser, &visfakes.FakeVisualizer{})
+			cmd.input = strings.NewReader(test.Input)
+
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			used, unused := fakeParser, fakeJSONParser
+			if test.JSON {
+				used, unused = fakeJSONParser, fakeParser
+			}
+			if used.ParseCallCount() != 1 || unused.ParseCallCount() != 0 {
+				t.Error("Assertion failed! Expected input to be parsed by the parser of its format")
+			}
+		})
+	}
+}
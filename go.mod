@@ -5,7 +5,9 @@ go 1.13
 require (
 	github.com/dave/dst v0.26.0
 	github.com/spf13/cobra v1.0.0
+	github.com/spf13/pflag v1.0.3
 	github.com/stretchr/testify v1.6.1 // indirect
+	golang.org/x/mod v0.3.0
 	golang.org/x/tools v0.0.0-20200822203824-307de81be3f4
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 )
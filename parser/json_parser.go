@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+type jsonField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// jsonEvent is a line of a trace printed in JSON format.
+type jsonEvent struct {
+	Event     string      `json:"event"`
+	Func      string      `json:"func"`
+	Caller    string      `json:"caller"`
+	Position  string      `json:"position"`
+	Receiver  *jsonField  `json:"receiver"`
+	Args      []jsonField `json:"args"`
+	Results   []jsonField `json:"results"`
+	CallID    string      `json:"callID"`
+	Goroutine string      `json:"goroutine"`
+	Timestamp string      `json:"timestamp"`
+	Duration  string      `json:"duration"`
+}
+
+type jsonParser struct {
+}
+
+// NewJSONParser returns a parser of traces printed in JSON format, one JSON object per line.
+func NewJSONParser() Parser {
+	return &jsonParser{}
+}
+
+func (p *jsonParser) Parse(in io.Reader) ([]FuncEvent, error) {
+	var events []FuncEvent
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		row := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(row, "{") {
+			continue
+		}
+		var je jsonEvent
+		if err := json.Unmarshal([]byte(row), &je); err != nil { // Not a trace line
+			continue
+		}
+		eventTime, err := parseTime(je.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		switch je.Event {
+		case "enter":
+			event := &InvocationEvent{
+				Callee:      normalizeFuncName(je.Func),
+				Caller:      normalizeFuncName(je.Caller),
+				Args:        formatJSONArgs(je),
+				CallID:      je.CallID,
+				GoroutineID: je.Goroutine,
+				Time:        eventTime,
+				Truncated:   truncatedFields(je.Args...),
+			}
+			if je.Receiver != nil && len(je.Receiver.Name) > 0 {
+				event.Receiver = je.Receiver.Value
+			}
+			if je.Receiver != nil && truncatedFields(*je.Receiver) {
+				event.Truncated = true
+			}
+			events = append(events, event)
+		case "exit":
+			duration, err := parseDuration(je.Duration)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, &ReturningEvent{
+				Callee:      normalizeFuncName(je.Func),
+				Caller:      normalizeFuncName(je.Caller),
+				Results:     formatJSONResults(je.Results),
+				CallID:      je.CallID,
+				GoroutineID: je.Goroutine,
+				Time:        eventTime,
+				Duration:    duration,
+				Truncated:   truncatedFields(je.Results...),
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// formatJSONArgs formats the arguments the way they are printed in text format e.g. "at main.go:12 with args (i=5) (<unnamed>)".
+func formatJSONArgs(je jsonEvent) string {
+	var parts []string
+	if len(je.Position) > 0 {
+		parts = append(parts, "at "+je.Position)
+	}
+	if len(je.Args) > 0 {
+		parts = append(parts, "with args")
+		for _, arg := range je.Args {
+			if len(arg.Name) == 0 {
+				parts = append(parts, "(<unnamed>)")
+			} else {
+				parts = append(parts, "("+arg.Name+"="+arg.Value+")")
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatJSONResults formats the results the way they are printed in text format e.g. "with results (r=1) (2)".
+func formatJSONResults(results []jsonField) string {
+	if len(results) == 0 {
+		return ""
+	}
+	parts := []string{"with results"}
+	for _, result := range results {
+		if len(result.Name) == 0 {
+			parts = append(parts, "("+result.Value+")")
+		} else {
+			parts = append(parts, "("+result.Name+"="+result.Value+")")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// truncatedFields reports whether the value of any of the fields was truncated by the instrumented code.
+func truncatedFields(fields ...jsonField) bool {
+	for _, field := range fields {
+		if _, _, truncated := ParseTruncatedValue(field.Value); truncated {
+			return true
+		}
+	}
+	return false
+}
@@ -2,8 +2,12 @@ package parser
 
 import (
 	"bufio"
+	"fmt"
 	"io"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 //go:generate counterfeiter . Parser
@@ -22,6 +26,7 @@ type FuncEvent interface {
 	GetCaller() string
 	GetCallee() string
 	GetCallID() string
+	GetGoroutineID() string
 }
 
 type InvocationEvent struct {
@@ -29,6 +34,15 @@ type InvocationEvent struct {
 	Callee string
 	CallID string
 	Args   string
+	// Receiver identifies the receiver a method was invoked on: its address for pointer receivers or its value otherwise.
+	// Empty for functions and methods which receivers were not printed.
+	Receiver string
+	// GoroutineID is the ID of the goroutine the function was invoked on. Empty for traces without goroutine IDs.
+	GoroutineID string
+	// Time is the time of the invocation. Zero for traces without timestamps.
+	Time time.Time
+	// Truncated reports whether the receiver or any of the args was truncated, see ParseTruncatedValue.
+	Truncated bool
 }
 
 func (ie *InvocationEvent) GetCaller() string {
@@ -43,10 +57,23 @@ func (ie *InvocationEvent) GetCallID() string {
 	return ie.CallID
 }
 
+func (ie *InvocationEvent) GetGoroutineID() string {
+	return ie.GoroutineID
+}
+
 type ReturningEvent struct {
-	Caller string
-	Callee string
-	CallID string
+	Caller  string
+	Callee  string
+	CallID  string
+	Results string
+	// GoroutineID is the ID of the goroutine the function returned on. Empty for traces without goroutine IDs.
+	GoroutineID string
+	// Time is the time of the return. Zero for traces without timestamps.
+	Time time.Time
+	// Duration is the time elapsed since the invocation. Zero for traces without durations.
+	Duration time.Duration
+	// Truncated reports whether any of the results was truncated, see ParseTruncatedValue.
+	Truncated bool
 }
 
 func (re *ReturningEvent) GetCaller() string {
@@ -61,6 +88,35 @@ func (re *ReturningEvent) GetCallID() string {
 	return re.CallID
 }
 
+func (re *ReturningEvent) GetGoroutineID() string {
+	return re.GoroutineID
+}
+
+const receiverPrefix = "with receiver ("
+
+// callIDField separates the message of a trace line from its fields, e.g. "; callID=...; goroutine=...; time=...; duration=...".
+const callIDField = "; callID="
+
+// truncatedValueRegex matches the marker appended to values truncated by the instrumented code, e.g. [1 2 3...(+1234 bytes).
+var truncatedValueRegex = regexp.MustCompile(`\.\.\.\(\+(\d+) bytes\)$`)
+
+// truncatedArgRegex matches a truncated value at the end of a printed arg, receiver or result, e.g. (s=[1 2 3...(+1234 bytes)).
+var truncatedArgRegex = regexp.MustCompile(`\.\.\.\(\+\d+ bytes\)\)`)
+
+// ParseTruncatedValue splits a value truncated by the instrumented code into the printed prefix and the number of omitted bytes.
+// Values which were not truncated are returned as is along with false.
+func ParseTruncatedValue(value string) (string, int, bool) {
+	match := truncatedValueRegex.FindStringSubmatchIndex(value)
+	if match == nil {
+		return value, 0, false
+	}
+	omitted, err := strconv.Atoi(value[match[2]:match[3]])
+	if err != nil {
+		return value, 0, false
+	}
+	return value[:match[0]], omitted, true
+}
+
 type parser struct {
 }
 
@@ -73,26 +129,46 @@ func (p *parser) Parse(in io.Reader) ([]FuncEvent, error) {
 	scanner := bufio.NewScanner(in)
 	for scanner.Scan() {
 		row := scanner.Text()
-		lastSemicolon := strings.LastIndex(row, ";")
-		msg := row[:lastSemicolon]
-		secondHalf := row[lastSemicolon+1:]
-		callID := strings.Split(secondHalf, "=")[1]
+		fieldsStart := strings.LastIndex(row, callIDField)
+		if fieldsStart == -1 { // Not a trace line
+			continue
+		}
+		msg := row[:fieldsStart]
+		fields := parseFields(row[fieldsStart+2:])
+		eventTime, err := parseTime(fields["time"])
+		if err != nil {
+			return nil, err
+		}
 		if strings.HasPrefix(msg, "Entering function") {
 			words := strings.Split(msg, " ")
+			receiver, args := parseReceiver(strings.Join(words[6:], " "))
 			events = append(events, &InvocationEvent{
-				Callee: normalizeFuncName(words[2]),
-				Caller: normalizeFuncName(words[5]),
-				Args:   strings.Join(words[6:], " "),
-				CallID: callID,
+				Callee:      normalizeFuncName(words[2]),
+				Caller:      normalizeFuncName(words[5]),
+				Args:        args,
+				CallID:      fields["callID"],
+				Receiver:    receiver,
+				GoroutineID: fields["goroutine"],
+				Time:        eventTime,
+				Truncated:   truncatedArgRegex.MatchString(msg),
 			})
 		}
 
 		if strings.HasPrefix(msg, "Exiting function") {
+			duration, err := parseDuration(fields["duration"])
+			if err != nil {
+				return nil, err
+			}
 			words := strings.Split(msg, " ")
 			events = append(events, &ReturningEvent{
-				Callee: normalizeFuncName(words[2]),
-				Caller: normalizeFuncName(words[5]),
-				CallID: callID,
+				Callee:      normalizeFuncName(words[2]),
+				Caller:      normalizeFuncName(words[5]),
+				Results:     strings.Join(words[6:], " "),
+				CallID:      fields["callID"],
+				GoroutineID: fields["goroutine"],
+				Time:        eventTime,
+				Duration:    duration,
+				Truncated:   truncatedArgRegex.MatchString(msg),
 			})
 		}
 	}
@@ -103,6 +179,59 @@ func (p *parser) Parse(in io.Reader) ([]FuncEvent, error) {
 	return events, nil
 }
 
+// parseFields parses the fields of a trace line e.g. "callID=...; goroutine=...; time=...; duration=..." into a map.
+func parseFields(s string) map[string]string {
+	fields := make(map[string]string)
+	for _, field := range strings.Split(s, "; ") {
+		keyValue := strings.SplitN(field, "=", 2)
+		if len(keyValue) == 2 {
+			fields[keyValue[0]] = keyValue[1]
+		}
+	}
+	return fields
+}
+
+func parseTime(s string) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed parsing time %s: %v", s, err)
+	}
+	return t, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if len(s) == 0 {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed parsing duration %s: %v", s, err)
+	}
+	return d, nil
+}
+
+// parseReceiver splits the receiver from the rest of the message e.g. "with receiver (t=0xc00001c030) with args (i=5)".
+func parseReceiver(msg string) (string, string) {
+	if !strings.HasPrefix(msg, receiverPrefix) {
+		return "", msg
+	}
+	end := strings.Index(msg, ") with args (")
+	if end == -1 {
+		end = len(msg) - 1
+	}
+	receiver := msg[len(receiverPrefix):end]
+	args := strings.TrimPrefix(msg[end+1:], " ")
+
+	nameEnd := strings.Index(receiver, "=")
+	if nameEnd == -1 { // Unnamed receivers are not printed
+		return "", args
+	}
+	return receiver[nameEnd+1:], args
+}
+
 func normalizeFuncName(funcName string) string {
 	return funcName[strings.LastIndex(funcName, "/")+1:]
 }
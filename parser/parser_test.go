@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestParser_Parse(t *testing.T) {
@@ -12,7 +13,7 @@ Entering function main.foo called by main.main with args (5) (false); callID=973
 Entering function main.bar called by main.foo with args (test string); callID=6c294dfd-4c6a-39b1-474e-314bee73f514
 Entering function main.baz called by main.bar; callID=a019a297-0a6e-a792-0e3f-23c33a44622f
 Exiting function main.baz called by main.bar; callID=a019a297-0a6e-a792-0e3f-23c33a44622f
-Exiting function main.bar called by main.foo; callID=6c294dfd-4c6a-39b1-474e-314bee73f514
+Exiting function main.bar called by main.foo with results (5) (<nil>); callID=6c294dfd-4c6a-39b1-474e-314bee73f514
 Exiting function main.foo called by main.main; callID=973355a9-2ec6-095c-9137-7a1081ac0a5f
 Exiting function main.main called by runtime.main; callID=1d8ca74e-c860-8a75-fc36-fe6d34350f0c`
 
@@ -45,9 +46,10 @@ Exiting function main.main called by runtime.main; callID=1d8ca74e-c860-8a75-fc3
 			CallID: "a019a297-0a6e-a792-0e3f-23c33a44622f",
 		},
 		&ReturningEvent{
-			Caller: "main.foo",
-			Callee: "main.bar",
-			CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514",
+			Caller:  "main.foo",
+			Callee:  "main.bar",
+			CallID:  "6c294dfd-4c6a-39b1-474e-314bee73f514",
+			Results: "with results (5) (<nil>)",
 		},
 		&ReturningEvent{
 			Caller: "main.main",
@@ -70,3 +72,266 @@ Exiting function main.main called by runtime.main; callID=1d8ca74e-c860-8a75-fc3
 		t.Error("Assertion Failed!")
 	}
 }
+
+func TestParser_ParseReceivers(t *testing.T) {
+	input := `Entering function main.(*T).foo called by main.main with receiver (t=0xc00001c030) with args (i=5); callID=973355a9-2ec6-095c-9137-7a1081ac0a5f
+Entering function main.T.bar called by main.(*T).foo with receiver (t={1 2}); callID=6c294dfd-4c6a-39b1-474e-314bee73f514
+Entering function main.T.baz called by main.T.bar with receiver (<unnamed>) with args (s=test string); callID=a019a297-0a6e-a792-0e3f-23c33a44622f`
+
+	expected := []FuncEvent{
+		&InvocationEvent{
+			Caller:   "main.main",
+			Callee:   "main.(*T).foo",
+			CallID:   "973355a9-2ec6-095c-9137-7a1081ac0a5f",
+			Args:     "with args (i=5)",
+			Receiver: "0xc00001c030",
+		},
+		&InvocationEvent{
+			Caller:   "main.(*T).foo",
+			Callee:   "main.T.bar",
+			CallID:   "6c294dfd-4c6a-39b1-474e-314bee73f514",
+			Receiver: "{1 2}",
+		},
+		&InvocationEvent{
+			Caller: "main.T.bar",
+			Callee: "main.T.baz",
+			CallID: "a019a297-0a6e-a792-0e3f-23c33a44622f",
+			Args:   "with args (s=test string)",
+		},
+	}
+
+	actual, err := NewParser().Parse(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Assertion Failed! Expected: %v but got: %v", expected, actual)
+	}
+}
+
+func TestParser_ParseFields(t *testing.T) {
+	input := `Entering function main.main called by runtime.main; callID=1d8ca74e-c860-8a75-fc36-fe6d34350f0c; goroutine=1; time=2020-05-01T10:00:00.000001Z
+some output of the program
+Exiting function main.main called by runtime.main; callID=1d8ca74e-c860-8a75-fc36-fe6d34350f0c; goroutine=1; time=2020-05-01T10:00:01.5Z; duration=1.499999s`
+
+	expected := []FuncEvent{
+		&InvocationEvent{
+			Caller:      "runtime.main",
+			Callee:      "main.main",
+			CallID:      "1d8ca74e-c860-8a75-fc36-fe6d34350f0c",
+			GoroutineID: "1",
+			Time:        time.Date(2020, 5, 1, 10, 0, 0, 1000, time.UTC),
+		},
+		&ReturningEvent{
+			Caller:      "runtime.main",
+			Callee:      "main.main",
+			CallID:      "1d8ca74e-c860-8a75-fc36-fe6d34350f0c",
+			GoroutineID: "1",
+			Time:        time.Date(2020, 5, 1, 10, 0, 1, 500000000, time.UTC),
+			Duration:    1499999 * time.Microsecond,
+		},
+	}
+
+	actual, err := NewParser().Parse(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Assertion Failed! Expected: %v but got: %v", expected, actual)
+	}
+}
+
+func TestParser_ParseInvalidDuration(t *testing.T) {
+	input := `Exiting function main.main called by runtime.main; callID=1d8ca74e-c860-8a75-fc36-fe6d34350f0c; duration=long`
+
+	if _, err := NewParser().Parse(bytes.NewBufferString(input)); err == nil {
+		t.Error("Assertion Failed! Expected error parsing invalid duration")
+	}
+}
+
+func TestJSONParser_Parse(t *testing.T) {
+	input := `{"event":"enter","func":"main.main","caller":"runtime.main","callID":"1d8ca74e-c860-8a75-fc36-fe6d34350f0c","goroutine":"1","timestamp":"2020-05-01T10:00:00Z"}
+{"level":"info","msg":"program output"}
+{"event":"enter","func":"github.com/DimitarPetrov/printracer/a.(*T).foo","caller":"main.main","receiver":{"name":"t","value":"0xc00001c030"},"args":[{"name":"s","value":"a; b) (c"},{"name":""}],"callID":"973355a9-2ec6-095c-9137-7a1081ac0a5f","goroutine":"1","timestamp":"2020-05-01T10:00:00.5Z"}
+{"event":"enter","func":"main.main.func1","caller":"main.main","position":"main.go:12","callID":"6c294dfd-4c6a-39b1-474e-314bee73f514","goroutine":"2","timestamp":"2020-05-01T10:00:00.6Z"}
+{"event":"exit","func":"github.com/DimitarPetrov/printracer/a.(*T).foo","caller":"main.main","results":[{"name":"r","value":"1"},{"name":"","value":"<nil>"}],"callID":"973355a9-2ec6-095c-9137-7a1081ac0a5f","goroutine":"1","timestamp":"2020-05-01T10:00:01Z","duration":"500ms"}`
+
+	expected := []FuncEvent{
+		&InvocationEvent{
+			Caller:      "runtime.main",
+			Callee:      "main.main",
+			CallID:      "1d8ca74e-c860-8a75-fc36-fe6d34350f0c",
+			GoroutineID: "1",
+			Time:        time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+		},
+		&InvocationEvent{
+			Caller:      "main.main",
+			Callee:      "a.(*T).foo",
+			CallID:      "973355a9-2ec6-095c-9137-7a1081ac0a5f",
+			Args:        "with args (s=a; b) (c) (<unnamed>)",
+			Receiver:    "0xc00001c030",
+			GoroutineID: "1",
+			Time:        time.Date(2020, 5, 1, 10, 0, 0, 500000000, time.UTC),
+		},
+		&InvocationEvent{
+			Caller:      "main.main",
+			Callee:      "main.main.func1",
+			CallID:      "6c294dfd-4c6a-39b1-474e-314bee73f514",
+			Args:        "at main.go:12",
+			GoroutineID: "2",
+			Time:        time.Date(2020, 5, 1, 10, 0, 0, 600000000, time.UTC),
+		},
+		&ReturningEvent{
+			Caller:      "main.main",
+			Callee:      "a.(*T).foo",
+			CallID:      "973355a9-2ec6-095c-9137-7a1081ac0a5f",
+			Results:     "with results (r=1) (<nil>)",
+			GoroutineID: "1",
+			Time:        time.Date(2020, 5, 1, 10, 0, 1, 0, time.UTC),
+			Duration:    500 * time.Millisecond,
+		},
+	}
+
+	actual, err := NewJSONParser().Parse(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Assertion Failed! Expected: %v but got: %v", expected, actual)
+	}
+}
+
+func TestParser_ParseTruncatedValues(t *testing.T) {
+	input := `Entering function main.(*T).foo called by main.main with receiver (t={1 2...(+10 bytes)) with args (s=[1 2 3...(+1234 bytes)) (i=5); callID=973355a9-2ec6-095c-9137-7a1081ac0a5f
+Entering function main.bar called by main.(*T).foo with args (s=...(+1 bytes) in text); callID=6c294dfd-4c6a-39b1-474e-314bee73f514
+Exiting function main.bar called by main.(*T).foo with results (<nil>); callID=6c294dfd-4c6a-39b1-474e-314bee73f514
+Exiting function main.(*T).foo called by main.main with results (r=abc...(+3 bytes)); callID=973355a9-2ec6-095c-9137-7a1081ac0a5f`
+
+	expected := []FuncEvent{
+		&InvocationEvent{
+			Caller:    "main.main",
+			Callee:    "main.(*T).foo",
+			CallID:    "973355a9-2ec6-095c-9137-7a1081ac0a5f",
+			Args:      "with args (s=[1 2 3...(+1234 bytes)) (i=5)",
+			Receiver:  "{1 2...(+10 bytes)",
+			Truncated: true,
+		},
+		&InvocationEvent{
+			Caller: "main.(*T).foo",
+			Callee: "main.bar",
+			CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514",
+			Args:   "with args (s=...(+1 bytes) in text)",
+		},
+		&ReturningEvent{
+			Caller:  "main.(*T).foo",
+			Callee:  "main.bar",
+			CallID:  "6c294dfd-4c6a-39b1-474e-314bee73f514",
+			Results: "with results (<nil>)",
+		},
+		&ReturningEvent{
+			Caller:    "main.main",
+			Callee:    "main.(*T).foo",
+			CallID:    "973355a9-2ec6-095c-9137-7a1081ac0a5f",
+			Results:   "with results (r=abc...(+3 bytes))",
+			Truncated: true,
+		},
+	}
+
+	actual, err := NewParser().Parse(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Assertion Failed! Expected: %v but got: %v", expected, actual)
+	}
+}
+
+func TestJSONParser_ParseTruncatedValues(t *testing.T) {
+	input := `{"event":"enter","func":"main.(*T).foo","caller":"main.main","receiver":{"name":"t","value":"{1 2...(+10 bytes)"},"callID":"973355a9-2ec6-095c-9137-7a1081ac0a5f"}
+{"event":"enter","func":"main.bar","caller":"main.(*T).foo","args":[{"name":"s","value":"[1 2 3...(+1234 bytes)"}],"callID":"6c294dfd-4c6a-39b1-474e-314bee73f514"}
+{"event":"exit","func":"main.bar","caller":"main.(*T).foo","results":[{"name":"","value":"...(+1 bytes) in text"}],"callID":"6c294dfd-4c6a-39b1-474e-314bee73f514"}`
+
+	expected := []FuncEvent{
+		&InvocationEvent{
+			Caller:    "main.main",
+			Callee:    "main.(*T).foo",
+			CallID:    "973355a9-2ec6-095c-9137-7a1081ac0a5f",
+			Receiver:  "{1 2...(+10 bytes)",
+			Truncated: true,
+		},
+		&InvocationEvent{
+			Caller:    "main.(*T).foo",
+			Callee:    "main.bar",
+			CallID:    "6c294dfd-4c6a-39b1-474e-314bee73f514",
+			Args:      "with args (s=[1 2 3...(+1234 bytes))",
+			Truncated: true,
+		},
+		&ReturningEvent{
+			Caller:  "main.(*T).foo",
+			Callee:  "main.bar",
+			CallID:  "6c294dfd-4c6a-39b1-474e-314bee73f514",
+			Results: "with results (...(+1 bytes) in text)",
+		},
+	}
+
+	actual, err := NewJSONParser().Parse(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Assertion Failed! Expected: %v but got: %v", expected, actual)
+	}
+}
+
+func TestParseTruncatedValue(t *testing.T) {
+	tests := []struct {
+		Value     string
+		Prefix    string
+		Omitted   int
+		Truncated bool
+	}{
+		{Value: "[1 2 3...(+1234 bytes)", Prefix: "[1 2 3", Omitted: 1234, Truncated: true},
+		{Value: "...(+1 bytes)", Prefix: "", Omitted: 1, Truncated: true},
+		{Value: "[1 2 3]", Prefix: "[1 2 3]"},
+		{Value: "...(+1 bytes) in text", Prefix: "...(+1 bytes) in text"},
+		{Value: "6162...(40 bytes)", Prefix: "6162...(40 bytes)"},
+	}
+
+	for _, test := range tests {
+		prefix, omitted, truncated := ParseTruncatedValue(test.Value)
+		if prefix != test.Prefix || omitted != test.Omitted || truncated != test.Truncated {
+			t.Errorf("Assertion Failed! Expected %s, %d, %v but got %s, %d, %v for %s", test.Prefix, test.Omitted, test.Truncated, prefix, omitted, truncated, test.Value)
+		}
+	}
+}
+
+func TestParser_ParseCallIDs(t *testing.T) {
+	callIDs := []string{"42", "1234-42", "019a1c6e-3a5b-7000-8d2f-4e6b1a2c3d4e", "973355a9-2ec6-095c-9137-7a1081ac0a5f"}
+
+	for _, callID := range callIDs {
+		input := `Entering function main.foo called by main.main with args (i=5); callID=` + callID + `; goroutine=1
+Exiting function main.foo called by main.main; callID=` + callID + `; goroutine=1
+{"event":"enter","func":"main.foo","caller":"main.main","callID":"` + callID + `","goroutine":"1"}
+{"event":"exit","func":"main.foo","caller":"main.main","callID":"` + callID + `","goroutine":"1"}`
+
+		for _, p := range []Parser{NewParser(), NewJSONParser()} {
+			events, err := p.Parse(bytes.NewBufferString(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 2 {
+				t.Fatalf("Assertion Failed! Expected 2 events but got %d", len(events))
+			}
+			for _, event := range events {
+				if event.GetCallID() != callID || event.GetGoroutineID() != "1" {
+					t.Errorf("Assertion Failed! Expected call ID %s and goroutine 1 but got %s and %s", callID, event.GetCallID(), event.GetGoroutineID())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package rt
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// callCounter is the number of calls traced by the process, used by all the strategies of call IDs.
+	callCounter uint64
+	// processPrefix prefixes the call IDs of ProcessCallIDs strategy.
+	processPrefix = strconv.Itoa(os.Getpid()) + "-"
+	// processRandom fills the bits of UUIDv7CallIDs which are neither the timestamp nor the counter,
+	// so that the call IDs of different processes do not collide.
+	processRandom = newProcessRandom()
+)
+
+func newProcessRandom() uint64 {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return binary.BigEndian.Uint64(b[:])
+}
+
+func newCounterCallID() string {
+	return strconv.FormatUint(atomic.AddUint64(&callCounter, 1), 10)
+}
+
+func newProcessCallID() string {
+	return processPrefix + strconv.FormatUint(atomic.AddUint64(&callCounter, 1), 10)
+}
+
+// newUUIDv7CallID returns a UUID version 7 (see RFC 9562) with the following layout:
+// 48 bits of unix timestamp in milliseconds, 4 bits of version, 12 bits of the counter, 2 bits of variant,
+// 20 more bits of the counter and 42 random bits generated once per process.
+// The IDs are ordered by time at millisecond precision, and unique within the process unless
+// 2^32 calls are traced in a single millisecond.
+func newUUIDv7CallID() string {
+	counter := atomic.AddUint64(&callCounter, 1)
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[0:8], uint64(time.Now().UnixNano()/int64(time.Millisecond))<<16|0x7000|(counter>>20)&0x0fff)
+	binary.BigEndian.PutUint64(id[8:16], 0x8000000000000000|(counter&0xfffff)<<42|processRandom&0x3ffffffffff)
+
+	var s [36]byte
+	hex.Encode(s[0:8], id[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], id[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], id[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], id[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], id[10:])
+	return string(s[:])
+}
@@ -0,0 +1,68 @@
+package rt
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCallIDs(t *testing.T) {
+	tests := []struct {
+		CallIDs  string
+		Expected *regexp.Regexp
+	}{
+		{CallIDs: "", Expected: regexp.MustCompile(`^\d+$`)},
+		{CallIDs: CounterCallIDs, Expected: regexp.MustCompile(`^\d+$`)},
+		{CallIDs: ProcessCallIDs, Expected: regexp.MustCompile(`^\d+-\d+$`)},
+		{CallIDs: UUIDv7CallIDs, Expected: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+	}
+
+	for _, test := range tests {
+		c := Config{CallIDs: test.CallIDs}
+		ids := make(map[string]bool)
+		for i := 0; i < 1000; i++ {
+			id := c.newCallID()
+			if !test.Expected.MatchString(id) {
+				t.Errorf("Assertion failed! Expected %s call ID matching %s but got %s", test.CallIDs, test.Expected, id)
+			}
+			if ids[id] {
+				t.Errorf("Assertion failed! Expected unique %s call IDs but got %s twice", test.CallIDs, id)
+			}
+			ids[id] = true
+		}
+	}
+}
+
+func TestUUIDv7CallIDsAreOrderedByTime(t *testing.T) {
+	before := time.Now().UnixNano() / int64(time.Millisecond)
+	first := newUUIDv7CallID()
+	time.Sleep(2 * time.Millisecond)
+	second := newUUIDv7CallID()
+
+	if first >= second {
+		t.Errorf("Assertion failed! Expected %s to be ordered before %s", first, second)
+	}
+	timestamp, err := strconv.ParseInt(strings.Replace(first[:13], "-", "", 1), 16, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timestamp < before || timestamp > before+1 {
+		t.Errorf("Assertion failed! Expected %s to start with the unix time in milliseconds %x", first, before)
+	}
+}
+
+func TestEnterCallIDs(t *testing.T) {
+	Configure(Config{CallIDs: ProcessCallIDs})
+	var buff bytes.Buffer
+	output = &buff
+	defer Configure(Config{})
+
+	traced()
+
+	if !regexp.MustCompile(`; callID=\d+-\d+; `).MatchString(buff.String()) {
+		t.Errorf("Assertion failed! Expected process prefixed call IDs but got: %s", buff.String())
+	}
+}
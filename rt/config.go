@@ -0,0 +1,123 @@
+package rt
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// MaxArgLenEnv is the environment variable which overrides the configured maximum length of printed values at runtime.
+const MaxArgLenEnv = "PRINTRACER_MAX_ARG_LEN"
+
+const (
+	// TextFormat prints every event as a line of text, e.g.
+	// Entering function main.foo called by main.main with args (i=5); callID=...; goroutine=1; time=...
+	TextFormat = "text"
+	// JSONFormat prints every event as a single line JSON object, e.g.
+	// {"event":"enter","func":"main.foo","caller":"main.main","args":[{"name":"i","value":"5"}],"callID":"...","goroutine":"1","timestamp":"..."}
+	JSONFormat = "json"
+)
+
+const (
+	// CounterCallIDs identifies the calls by a counter, e.g. 42. It is the cheapest strategy, but the IDs are unique
+	// only within the traced process.
+	CounterCallIDs = "counter"
+	// ProcessCallIDs identifies the calls by a counter prefixed with the ID of the traced process, e.g. 1234-42,
+	// so that the traces of several processes can be written to the same output.
+	ProcessCallIDs = "process"
+	// UUIDv7CallIDs identifies the calls by time-ordered UUIDs (version 7), e.g. 019a1c6e-3a5b-7000-8d2f-4e6b1a2c3d4e.
+	UUIDv7CallIDs = "uuid7"
+)
+
+// Config controls how the trace of instrumented code is printed. Zero values stand for the defaults.
+type Config struct {
+	// Format is the format of the trace: TextFormat (default) or JSONFormat.
+	Format string
+	// Output is where the trace is written: StdoutOutput (default), StderrOutput or a path of a file.
+	// Overridden by the PRINTRACER_OUTPUT environment variable.
+	Output string
+	// MaxArgLen is the maximum length in bytes of a printed argument, receiver or result. Longer values are truncated
+	// and marked with the number of omitted bytes, e.g. [1 2 3...(+1234 bytes). Zero (default) means no limit.
+	// Overridden by the PRINTRACER_MAX_ARG_LEN environment variable.
+	MaxArgLen int
+	// SampleRate traces 1 in SampleRate calls of every function. The calls made within a call which is not traced
+	// are not traced either, so that sampled traces are complete trees. Zero (default) traces all the calls.
+	SampleRate int
+	// RateLimit traces at most RateLimit calls of every function per second. Zero (default) means no limit.
+	RateLimit int
+	// MaxEvents stops the tracing of new calls once MaxEvents events were written. The returns of the calls in progress
+	// are still written, so that the trace ends with complete trees. Zero (default) means no limit.
+	MaxEvents int
+	// CallIDs is the strategy of the call IDs: CounterCallIDs (default), ProcessCallIDs or UUIDv7CallIDs.
+	CallIDs string
+}
+
+var (
+	configMutex sync.RWMutex
+	config      Config
+
+	maxArgLenEnvOnce sync.Once
+	maxArgLenEnv     *int
+)
+
+// Configure replaces the configuration of the tracing. Code instrumented with non-default options calls it
+// in the initializer of a package-level variable, e.g.:
+//
+//	var printracerConfig = prt.Configure(prt.Config{Format: prt.JSONFormat})
+//
+// The returned field is passed to Enter by every instrumented function of the package, which makes the functions
+// depend on the variable. Thus the configuration is applied before any of them is called, even by other
+// package-level initializers, which are run before init functions.
+func Configure(c Config) Field {
+	configMutex.Lock()
+	config = c
+	configMutex.Unlock()
+	resetOutput()
+	resetSampling()
+	return Field{kind: configField}
+}
+
+func currentConfig() Config {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return config
+}
+
+func (c Config) formatter() formatter {
+	if c.Format == JSONFormat {
+		return formatJSON
+	}
+	return formatText
+}
+
+// newCallID returns a new ID of a call according to the configured strategy.
+func (c Config) newCallID() string {
+	switch c.CallIDs {
+	case ProcessCallIDs:
+		return newProcessCallID()
+	case UUIDv7CallIDs:
+		return newUUIDv7CallID()
+	}
+	return newCounterCallID()
+}
+
+// maxArgLen returns the maximum length of printed values unless overridden by the environment.
+func (c Config) maxArgLen() int {
+	maxArgLenEnvOnce.Do(func() {
+		env := os.Getenv(MaxArgLenEnv)
+		if len(env) == 0 {
+			return
+		}
+		maxLen, err := strconv.Atoi(env)
+		if err != nil || maxLen < 0 {
+			fmt.Fprintf(os.Stderr, "printracer: invalid %s %s, expected a non-negative number of bytes\n", MaxArgLenEnv, env)
+			return
+		}
+		maxArgLenEnv = &maxLen
+	})
+	if maxArgLenEnv != nil {
+		return *maxArgLenEnv
+	}
+	return c.MaxArgLen
+}
@@ -0,0 +1,161 @@
+package rt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type eventKind string
+
+const (
+	enterEvent eventKind = "enter"
+	exitEvent  eventKind = "exit"
+)
+
+// formattedField is a name along with an already formatted value.
+type formattedField struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+// event is the invocation (enter) or the return (exit) of an instrumented function.
+type event struct {
+	kind        eventKind
+	funcName    string
+	caller      string
+	position    string
+	receiver    *formattedField
+	args        []formattedField
+	results     []formattedField
+	callID      string
+	goroutineID string
+	time        time.Time
+	duration    time.Duration
+}
+
+// addFields formats the fields of the event. Arguments and receivers without names are left without values,
+// while results are dereferenced. Values longer than maxLen bytes are truncated unless maxLen is zero.
+func (e *event) addFields(fields []Field, maxLen int) {
+	for _, field := range fields {
+		switch field.kind {
+		case positionField:
+			e.position = fmt.Sprint(field.value)
+		case receiverField:
+			receiver := formattedField{Name: field.name}
+			if len(field.name) > 0 && field.format != nil {
+				receiver.Value = truncate(field.format(field.value), maxLen)
+			} else if len(field.name) > 0 {
+				receiver.Value = truncate(formatReceiver(field.value), maxLen)
+			}
+			e.receiver = &receiver
+		case argField:
+			arg := formattedField{Name: field.name}
+			if len(field.name) > 0 && field.format != nil {
+				arg.Value = truncate(field.format(field.value), maxLen)
+			} else if len(field.name) > 0 {
+				arg.Value = truncate(fmt.Sprintf("%v", field.value), maxLen)
+			}
+			e.args = append(e.args, arg)
+		case testField:
+			e.args = append(e.args, formattedField{Name: field.name, Value: truncate(formatTest(field.value), maxLen)})
+		case resultField:
+			if field.format != nil {
+				e.results = append(e.results, formattedField{Name: field.name, Value: truncate(field.format(field.value), maxLen)})
+			} else {
+				e.results = append(e.results, formattedField{Name: field.name, Value: truncate(fmt.Sprintf("%v", dereference(field.value)), maxLen)})
+			}
+		}
+	}
+}
+
+// truncate shortens the value to at most maxLen bytes (without splitting runes) followed by a marker
+// of the number of omitted bytes, e.g. [1 2 3...(+1234 bytes). Values are not truncated if maxLen is zero.
+func truncate(value string, maxLen int) string {
+	if maxLen <= 0 || len(value) <= maxLen {
+		return value
+	}
+	end := maxLen
+	for end > 0 && !utf8.RuneStart(value[end]) {
+		end--
+	}
+	return fmt.Sprintf("%s...(+%d bytes)", value[:end], len(value)-end)
+}
+
+// formatTest formats the argument of a test by the name of the test.
+func formatTest(t interface{}) string {
+	named, ok := t.(Named)
+	if !ok || (reflect.ValueOf(named).Kind() == reflect.Ptr && reflect.ValueOf(named).IsNil()) {
+		return "<nil>"
+	}
+	return named.Name()
+}
+
+// formatReceiver formats pointer receivers by address and value receivers by value.
+func formatReceiver(receiver interface{}) string {
+	if reflect.ValueOf(receiver).Kind() == reflect.Ptr {
+		return fmt.Sprintf("%p", receiver)
+	}
+	return fmt.Sprintf("%v", receiver)
+}
+
+// Byte slices longer than that are shortened when printed in hex.
+const maxPrintedBytes = 32
+
+func formatRedacted(interface{}) string {
+	return "<redacted>"
+}
+
+func formatString(value interface{}) string {
+	return fmt.Sprintf("%q", value)
+}
+
+// formatError formats the error by its message. Errors panicking on Error(), e.g. nil pointers, are formatted with %v.
+func formatError(value interface{}) (formatted string) {
+	err, ok := value.(error)
+	if !ok || err == nil {
+		return "<nil>"
+	}
+	defer func() {
+		if recover() != nil {
+			formatted = fmt.Sprintf("%v", value)
+		}
+	}()
+	return err.Error()
+}
+
+func formatContext(value interface{}) string {
+	ctx, ok := value.(context.Context)
+	if !ok || ctx == nil {
+		return "<nil>"
+	}
+	var summary []string
+	if deadline, ok := ctx.Deadline(); ok {
+		summary = append(summary, "deadline="+deadline.Format(time.RFC3339Nano))
+	}
+	if err := ctx.Err(); err != nil {
+		summary = append(summary, "err="+err.Error())
+	}
+	if len(summary) == 0 {
+		return "context"
+	}
+	return "context(" + strings.Join(summary, ", ") + ")"
+}
+
+func formatType(value interface{}) string {
+	return fmt.Sprintf("%T", value)
+}
+
+func formatBytes(value interface{}) string {
+	b, ok := value.([]byte)
+	if !ok || b == nil {
+		return "<nil>"
+	}
+	if len(b) > maxPrintedBytes {
+		return fmt.Sprintf("%x...(%d bytes)", b[:maxPrintedBytes], len(b))
+	}
+	return fmt.Sprintf("%x", b)
+}
@@ -0,0 +1,96 @@
+package rt
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Printed in place of parameters which cannot be referred to, e.g. func(int) or func(_ int).
+const unnamedPlaceholder = "<unnamed>"
+
+type formatter func(e *event) string
+
+// formatText formats the event as a line like:
+// Entering function main.foo called by main.main at main.go:12 with receiver (t=0xc00001c030) with args (i=5); callID=...; goroutine=1; time=...
+// Exiting function main.foo called by main.main with results (r=1) (2); callID=...; goroutine=1; time=...; duration=...
+func formatText(e *event) string {
+	var b strings.Builder
+	if e.kind == enterEvent {
+		b.WriteString("Entering function ")
+	} else {
+		b.WriteString("Exiting function ")
+	}
+	b.WriteString(e.funcName + " called by " + e.caller)
+	if len(e.position) > 0 {
+		b.WriteString(" at " + e.position)
+	}
+	if e.receiver != nil {
+		b.WriteString(" with receiver " + formatTextField(*e.receiver, unnamedPlaceholder))
+	}
+	if len(e.args) > 0 {
+		b.WriteString(" with args")
+		for _, arg := range e.args {
+			b.WriteString(" " + formatTextField(arg, unnamedPlaceholder))
+		}
+	}
+	if len(e.results) > 0 {
+		b.WriteString(" with results")
+		for _, result := range e.results {
+			b.WriteString(" " + formatTextField(result, result.Value))
+		}
+	}
+	b.WriteString("; callID=" + e.callID + "; goroutine=" + e.goroutineID + "; time=" + e.time.Format(time.RFC3339Nano))
+	if e.kind == exitEvent {
+		b.WriteString("; duration=" + e.duration.String())
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// formatTextField formats a field as (name=value) or as (unnamed) when the field has no name.
+func formatTextField(field formattedField, unnamed string) string {
+	if len(field.Name) == 0 {
+		return "(" + unnamed + ")"
+	}
+	return "(" + field.Name + "=" + field.Value + ")"
+}
+
+type jsonEvent struct {
+	Event     eventKind        `json:"event"`
+	Func      string           `json:"func"`
+	Caller    string           `json:"caller"`
+	Position  string           `json:"position,omitempty"`
+	Receiver  *formattedField  `json:"receiver,omitempty"`
+	Args      []formattedField `json:"args,omitempty"`
+	Results   []formattedField `json:"results,omitempty"`
+	CallID    string           `json:"callID"`
+	Goroutine string           `json:"goroutine"`
+	Timestamp string           `json:"timestamp"`
+	Duration  string           `json:"duration,omitempty"`
+}
+
+// formatJSON formats the event as a single line JSON object like:
+// {"event":"enter","func":"main.foo","caller":"main.main","args":[{"name":"i","value":"5"}],"callID":"...","goroutine":"1","timestamp":"..."}
+func formatJSON(e *event) string {
+	je := jsonEvent{
+		Event:     e.kind,
+		Func:      e.funcName,
+		Caller:    e.caller,
+		Position:  e.position,
+		Receiver:  e.receiver,
+		Args:      e.args,
+		Results:   e.results,
+		CallID:    e.callID,
+		Goroutine: e.goroutineID,
+		Timestamp: e.time.Format(time.RFC3339Nano),
+	}
+	if e.kind == exitEvent {
+		je.Duration = e.duration.String()
+	}
+	data, err := json.Marshal(je)
+	if err != nil { // Cannot happen as all the fields are strings
+		return ""
+	}
+	return string(data) + "\n"
+}
@@ -0,0 +1,43 @@
+package rt
+
+import "sync"
+
+// formatting are the goroutines formatting the fields of an event, by goroutine ID. Every goroutine stores and deletes
+// its own ID only, which is the case sync.Map is meant for, so that traced goroutines do not contend with each other.
+var formatting sync.Map
+
+// isFormatting reports whether the goroutine is formatting the fields of an event. Calls of instrumented functions
+// made meanwhile, e.g. of the String method of a receiver, are not traced, so that they do not recurse infinitely.
+func isFormatting(goroutineID string) bool {
+	_, ok := formatting.Load(goroutineID)
+	return ok
+}
+
+// addFields formats the fields of the event on the goroutine, see isFormatting.
+// The goroutine is marked only while formatting fields which might call methods of the traced code.
+func addFields(e *event, fields []Field, maxLen int) {
+	if !mayCallMethods(fields) {
+		e.addFields(fields, maxLen)
+		return
+	}
+	formatting.Store(e.goroutineID, true)
+	defer formatting.Delete(e.goroutineID)
+	e.addFields(fields, maxLen)
+}
+
+// mayCallMethods reports whether formatting any of the fields might call methods of their values, e.g. String or Error.
+// Values of predeclared types, as well as positions and redacted values, are formatted without calling any.
+func mayCallMethods(fields []Field) bool {
+	for _, field := range fields {
+		if field.kind == positionField {
+			continue
+		}
+		switch field.value.(type) {
+		case nil, bool, string, []byte, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr,
+			float32, float64, complex64, complex128:
+			continue
+		}
+		return true
+	}
+	return false
+}
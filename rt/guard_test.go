@@ -0,0 +1,29 @@
+package rt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMayCallMethods(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Fields   []Field
+		Expected bool
+	}{
+		{Name: "NoFields", Expected: false},
+		{Name: "PredeclaredTypes", Fields: []Field{Arg("i", 1), Arg("s", "a"), Arg("b", []byte("a")), Arg("f", 1.5), Arg("n", nil)}, Expected: false},
+		{Name: "PositionAndRedacted", Fields: []Field{At("main.go:12"), Redacted("password"), RedactedReceiver("c")}, Expected: false},
+		{Name: "Stringer", Fields: []Field{Arg("i", 1), Receiver("s", stringer{})}, Expected: true},
+		{Name: "Error", Fields: []Field{Error("err", errors.New("a"))}, Expected: true},
+		{Name: "Result", Fields: []Field{Result("", new(int))}, Expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if mayCallMethods(test.Fields) != test.Expected {
+				t.Errorf("Assertion failed! Expected %v", test.Expected)
+			}
+		})
+	}
+}
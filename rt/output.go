@@ -0,0 +1,75 @@
+package rt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// OutputEnv is the environment variable which overrides the configured output of the trace at runtime.
+const OutputEnv = "PRINTRACER_OUTPUT"
+
+const (
+	// StdoutOutput writes the trace to the standard output. It is the default output.
+	StdoutOutput = "stdout"
+	// StderrOutput writes the trace to the standard error.
+	StderrOutput = "stderr"
+)
+
+var (
+	outputMutex sync.Mutex
+	// output is where the trace is written. Resolved on the first write after (re)configuration.
+	output     io.Writer
+	outputFile *os.File
+)
+
+// write writes the event to the output. Writes are serialized, so that lines of concurrent calls are not interleaved.
+func write(e *event) {
+	c := currentConfig()
+	line := c.formatter()(e)
+
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+	if output == nil {
+		output = openOutput(c.Output)
+	}
+	_, _ = io.WriteString(output, line)
+	atomic.AddUint64(&writtenEvents, 1)
+}
+
+// resetOutput closes the output, so that it is resolved again on the next write.
+func resetOutput() {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+	if outputFile != nil {
+		_ = outputFile.Close()
+		outputFile = nil
+	}
+	output = nil
+}
+
+// openOutput opens the configured output unless overridden by the environment.
+// Anything else than stdout and stderr is a path of a file the trace is appended to.
+func openOutput(configured string) io.Writer {
+	destination := configured
+	if env := os.Getenv(OutputEnv); len(env) > 0 {
+		destination = env
+	}
+
+	switch destination {
+	case "", StdoutOutput:
+		return os.Stdout
+	case StderrOutput:
+		return os.Stderr
+	}
+
+	f, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "printracer: failed opening trace output %s, tracing to stderr instead: %v\n", destination, err)
+		return os.Stderr
+	}
+	outputFile = f
+	return f
+}
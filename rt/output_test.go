@@ -0,0 +1,74 @@
+package rt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOutputToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "printracer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configured := filepath.Join(dir, "configured.txt")
+	overridden := filepath.Join(dir, "overridden.txt")
+
+	tests := []struct {
+		Name     string
+		Env      string
+		Expected string
+	}{
+		{Name: "Configured", Expected: configured},
+		{Name: "OverriddenByEnv", Env: overridden, Expected: overridden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if err := os.Setenv(OutputEnv, test.Env); err != nil {
+				t.Fatal(err)
+			}
+			Configure(Config{Output: configured})
+			defer func() {
+				Configure(Config{})
+				_ = os.Unsetenv(OutputEnv)
+				_ = os.Remove(test.Expected)
+			}()
+
+			const goroutines = 50
+			var wg sync.WaitGroup
+			for i := 0; i < goroutines; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					traced()
+				}()
+			}
+			wg.Wait()
+
+			data, err := ioutil.ReadFile(test.Expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if len(lines) != goroutines*4 {
+				t.Fatalf("Assertion failed! Expected %d lines but got %d", goroutines*4, len(lines))
+			}
+			if _, err := os.Stat(configured); test.Expected != configured && !os.IsNotExist(err) {
+				t.Error("Assertion failed! Expected configured output to be overridden by the environment")
+			}
+			line := regexp.MustCompile(`^(Entering|Exiting) function \S+ called by \S+.*; callID=\d+; goroutine=\d+; time=\S+(; duration=\S+)?$`)
+			for _, l := range lines {
+				if !line.MatchString(l) {
+					t.Errorf("Assertion failed! Unexpected line: %s", l)
+				}
+			}
+		})
+	}
+}
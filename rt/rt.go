@@ -0,0 +1,203 @@
+// Package rt is the runtime support of code instrumented by printracer.
+//
+// Every instrumented function starts with a single statement like:
+//
+//	defer prt.Enter(prt.Arg("i", i), prt.Arg("b", b))(prt.Result("", &printracerResult0))
+//
+// Enter prints the invocation of the function and returns a function which prints its return.
+package rt
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Printed in place of functions which cannot be resolved at runtime.
+const unknown = "unknown"
+
+type fieldKind int
+
+const (
+	argField fieldKind = iota
+	receiverField
+	positionField
+	resultField
+	testField
+	// configField is returned by Configure and ignored by Enter.
+	configField
+)
+
+// Field is a piece of information printed along with the invocation or the return of a function.
+type Field struct {
+	kind  fieldKind
+	name  string
+	value interface{}
+	// format formats the value of an argument, receiver or result. Arguments are formatted with %v,
+	// receivers and results as described by Receiver and Result if nil.
+	format func(value interface{}) string
+}
+
+// Arg is an argument of the function. Arguments without a name are printed as <unnamed>.
+func Arg(name string, value interface{}) Field {
+	return Field{kind: argField, name: name, value: value}
+}
+
+// Redacted is an argument holding sensitive data (e.g. a password). It is printed as <redacted> instead of by value.
+func Redacted(name string) Field {
+	return Field{kind: argField, name: name, format: formatRedacted}
+}
+
+// The following arguments are used instead of Arg by code instrumented with type information (printracer apply --types).
+
+// String is an argument of a string type. It is printed quoted, e.g. "a b".
+func String(name string, value interface{}) Field {
+	return Field{kind: argField, name: name, value: value, format: formatString}
+}
+
+// Error is an argument of a type implementing error. It is printed by its message.
+func Error(name string, err error) Field {
+	return Field{kind: argField, name: name, value: err, format: formatError}
+}
+
+// Context is an argument of type context.Context. It is printed as a short summary of its deadline and error,
+// e.g. context(deadline=2020-08-22T20:38:24Z, err=context canceled), instead of its internals.
+func Context(name string, ctx context.Context) Field {
+	return Field{kind: argField, name: name, value: ctx, format: formatContext}
+}
+
+// Type is an argument printed by its type only, e.g. func(int) error. Used for functions and channels.
+func Type(name string, value interface{}) Field {
+	return Field{kind: argField, name: name, value: value, format: formatType}
+}
+
+// Bytes is an argument of type []byte. It is printed in hex, shortened if longer than 32 bytes.
+func Bytes(name string, b []byte) Field {
+	return Field{kind: argField, name: name, value: b, format: formatBytes}
+}
+
+// Receiver is the receiver of a method. Pointer receivers are printed by address and value receivers by value.
+// Receivers without a name are printed as <unnamed>.
+func Receiver(name string, value interface{}) Field {
+	return Field{kind: receiverField, name: name, value: value}
+}
+
+// RedactedReceiver is a receiver holding sensitive data. It is printed as <redacted> instead of by value.
+func RedactedReceiver(name string) Field {
+	return Field{kind: receiverField, name: name, format: formatRedacted}
+}
+
+// Named is implemented by *testing.T, *testing.B, *testing.F and testing.TB.
+type Named interface {
+	Name() string
+}
+
+// Test is an argument of a test, benchmark, fuzz test or test helper, e.g. t *testing.T.
+// It is printed by the name of the running test, e.g. TestFoo/subtest, instead of by value.
+func Test(name string, t Named) Field {
+	return Field{kind: testField, name: name, value: t}
+}
+
+// At is the source position of a function literal, e.g. main.go:12.
+func At(position string) Field {
+	return Field{kind: positionField, value: position}
+}
+
+// Result is a result of the function. The pointer is dereferenced when the function returns.
+// Results without a name are printed by value only.
+func Result(name string, ptr interface{}) Field {
+	return Field{kind: resultField, name: name, value: ptr}
+}
+
+// RedactedResult is a result holding sensitive data. It is printed as <redacted> instead of by value,
+// so the pointer is never dereferenced.
+func RedactedResult(name string, ptr interface{}) Field {
+	return Field{kind: resultField, name: name, value: ptr, format: formatRedacted}
+}
+
+// Enter prints the invocation of the function calling it and returns a function printing its return.
+// The returned function is meant to be deferred, so that it is called when the function returns.
+// Nothing is formatted nor printed for functions which are disabled at runtime, see EnabledEnv and FilterEnv,
+// nor for calls which are not sampled, see Config, nor for calls made while formatting the fields of another call.
+func Enter(fields ...Field) func(results ...Field) {
+	if !isTraced() {
+		return skip
+	}
+	c := currentConfig()
+	goroutineID := currentGoroutineID()
+	if isFormatting(goroutineID) {
+		return skip
+	}
+	sampled, leave := sample(c, goroutineID)
+	if !sampled {
+		return func(...Field) {
+			leave()
+		}
+	}
+
+	funcName, caller := callers()
+	enter := &event{
+		kind:        enterEvent,
+		funcName:    funcName,
+		caller:      caller,
+		callID:      c.newCallID(),
+		goroutineID: goroutineID,
+		time:        time.Now(),
+	}
+	maxArgLen := c.maxArgLen()
+	addFields(enter, fields, maxArgLen)
+	write(enter)
+
+	return func(results ...Field) {
+		defer leave()
+		exit := &event{
+			kind:        exitEvent,
+			funcName:    enter.funcName,
+			caller:      enter.caller,
+			callID:      enter.callID,
+			goroutineID: enter.goroutineID,
+			time:        time.Now(),
+		}
+		exit.duration = exit.time.Sub(enter.time)
+		addFields(exit, results, maxArgLen)
+		write(exit)
+	}
+}
+
+func dereference(ptr interface{}) interface{} {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return ptr
+	}
+	return v.Elem().Interface()
+}
+
+// callers returns the names of the instrumented function and its caller.
+func callers() (string, string) {
+	funcName, caller := unknown, unknown
+	pcs := make([]uintptr, 2)
+	// Skip runtime.Callers, callers and Enter
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	if frame, more := frames.Next(); len(frame.Function) > 0 {
+		funcName = frame.Function
+		if more {
+			if frame, _ := frames.Next(); len(frame.Function) > 0 {
+				caller = frame.Function
+			}
+		}
+	}
+	return funcName, caller
+}
+
+// currentGoroutineID parses the ID of the current goroutine from the first line of its stack trace,
+// which looks like: goroutine 1 [running]:
+func currentGoroutineID() string {
+	stack := make([]byte, 64)
+	fields := strings.Fields(string(stack[:runtime.Stack(stack, false)]))
+	if len(fields) < 2 {
+		return unknown
+	}
+	return fields[1]
+}
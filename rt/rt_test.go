@@ -0,0 +1,316 @@
+package rt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type receiver struct {
+	a int
+}
+
+func (r *receiver) pointerMethod(i int, s ...string) (res int, err error) {
+	defer Enter(Receiver("r", r), Arg("i", i), Arg("s...", s))(Result("res", &res), Result("", &err))
+	return i + r.a, nil
+}
+
+func (r receiver) valueMethod(int) {
+	defer Enter(Receiver("r", r), Arg("", nil))()
+}
+
+type stringer struct {
+	a int
+}
+
+func (s stringer) String() (res string) {
+	defer Enter(Receiver("s", s))(Result("", &res))
+	return "stringer " + strconv.Itoa(s.a)
+}
+
+func testHelper(t *testing.T) {
+	defer Enter(Test("t", t))()
+}
+
+func typed(s string, err error, ctx context.Context, f func(int) error, c chan int, b []byte) {
+	defer Enter(String("s", s), Error("err", err), Context("ctx", ctx), Type("f", f), Type("c", c), Bytes("b", b))()
+}
+
+func redacted(user, password string) {
+	defer Enter(Arg("user", user), Redacted("password"))()
+}
+
+func (r receiver) redactedMethod() (token string, err error) {
+	defer Enter(RedactedReceiver("r"))(RedactedResult("token", &token), Result("", &err))
+	return "secret", nil
+}
+
+func traced() {
+	defer Enter()()
+	func() {
+		defer Enter(At("rt_test.go:25"))()
+	}()
+}
+
+func TestEnter(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		Name     string
+		Call     func()
+		Expected []string
+	}{
+		{
+			Name: "Function",
+			Call: traced,
+			Expected: []string{
+				`Entering function github.com/DimitarPetrov/printracer/rt.traced called by github.com/DimitarPetrov/printracer/rt.TestEnter.func\d+; `,
+				`Entering function github.com/DimitarPetrov/printracer/rt.traced.func1 called by github.com/DimitarPetrov/printracer/rt.traced at rt_test.go:25; `,
+				`Exiting function github.com/DimitarPetrov/printracer/rt.traced.func1 called by github.com/DimitarPetrov/printracer/rt.traced; `,
+				`Exiting function github.com/DimitarPetrov/printracer/rt.traced called by github.com/DimitarPetrov/printracer/rt.TestEnter.func\d+; `,
+			},
+		},
+		{
+			Name: "PointerMethod",
+			Call: func() { _, _ = (&receiver{a: 1}).pointerMethod(2, "a", "b") },
+			Expected: []string{
+				`Entering function github.com/DimitarPetrov/printracer/rt.\(\*receiver\).pointerMethod called by \S+ with receiver \(r=0x[0-9a-f]+\) with args \(i=2\) \(s...=\[a b\]\); `,
+				`Exiting function github.com/DimitarPetrov/printracer/rt.\(\*receiver\).pointerMethod called by \S+ with results \(res=3\) \(<nil>\); `,
+			},
+		},
+		{
+			Name: "ValueMethod",
+			Call: func() { receiver{a: 1}.valueMethod(2) },
+			Expected: []string{
+				`Entering function github.com/DimitarPetrov/printracer/rt.receiver.valueMethod called by \S+ with receiver \(r=\{1\}\) with args \(<unnamed>\); `,
+				`Exiting function github.com/DimitarPetrov/printracer/rt.receiver.valueMethod called by \S+; `,
+			},
+		},
+		{
+			Name: "StringerReceiver",
+			Call: func() { _ = stringer{a: 1}.String() },
+			Expected: []string{
+				`Entering function github.com/DimitarPetrov/printracer/rt.stringer.String called by \S+ with receiver \(s=stringer 1\); `,
+				`Exiting function github.com/DimitarPetrov/printracer/rt.stringer.String called by \S+ with results \(stringer 1\); `,
+			},
+		},
+		{
+			Name: "TestHelper",
+			Call: func() { testHelper(t); testHelper(nil) },
+			Expected: []string{
+				`Entering function github.com/DimitarPetrov/printracer/rt.testHelper called by \S+ with args \(t=TestEnter\); `,
+				`Exiting function github.com/DimitarPetrov/printracer/rt.testHelper called by \S+; `,
+				`Entering function github.com/DimitarPetrov/printracer/rt.testHelper called by \S+ with args \(t=<nil>\); `,
+				`Exiting function github.com/DimitarPetrov/printracer/rt.testHelper called by \S+; `,
+			},
+		},
+		{
+			Name: "RedactedArgs",
+			Call: func() { redacted("john", "pass") },
+			Expected: []string{
+				`Entering function github.com/DimitarPetrov/printracer/rt.redacted called by \S+ with args \(user=john\) \(password=<redacted>\); `,
+				`Exiting function github.com/DimitarPetrov/printracer/rt.redacted called by \S+; `,
+			},
+		},
+		{
+			Name: "RedactedReceiverAndResults",
+			Call: func() { _, _ = receiver{a: 1}.redactedMethod() },
+			Expected: []string{
+				`Entering function github.com/DimitarPetrov/printracer/rt.receiver.redactedMethod called by \S+ with receiver \(r=<redacted>\); `,
+				`Exiting function github.com/DimitarPetrov/printracer/rt.receiver.redactedMethod called by \S+ with results \(token=<redacted>\) \(<nil>\); `,
+			},
+		},
+		{
+			Name: "TypedArgs",
+			Call: func() {
+				typed("a b", errors.New("failed"), context.Background(), nil, make(chan int), []byte("ab"))
+				typed("", nil, canceled, func(int) error { return nil }, nil, make([]byte, 40))
+			},
+			Expected: []string{
+				`Entering function github.com/DimitarPetrov/printracer/rt.typed called by \S+ with args \(s="a b"\) \(err=failed\) \(ctx=context\) \(f=func\(int\) error\) \(c=chan int\) \(b=6162\); `,
+				`Exiting function github.com/DimitarPetrov/printracer/rt.typed called by \S+; `,
+				`Entering function github.com/DimitarPetrov/printracer/rt.typed called by \S+ with args \(s=""\) \(err=<nil>\) \(ctx=context\(err=context canceled\)\) \(f=func\(int\) error\) \(c=chan int\) \(b=0{64}...\(40 bytes\)\); `,
+				`Exiting function github.com/DimitarPetrov/printracer/rt.typed called by \S+; `,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var buff bytes.Buffer
+			output = &buff
+			defer func() {
+				output = nil
+			}()
+
+			test.Call()
+
+			lines := strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+			if len(lines) != len(test.Expected) {
+				t.Fatalf("Assertion failed! Expected %d lines but got: %s", len(test.Expected), buff.String())
+			}
+			for i, line := range lines {
+				if !regexp.MustCompile("^" + test.Expected[i]).MatchString(line) {
+					t.Errorf("Assertion failed! Expected line matching %s but got: %s", test.Expected[i], line)
+				}
+			}
+		})
+	}
+}
+
+func TestEnterFields(t *testing.T) {
+	var buff bytes.Buffer
+	output = &buff
+	defer func() {
+		output = nil
+	}()
+
+	traced()
+
+	fields := regexp.MustCompile(`; callID=(\d+); goroutine=(\d+); time=(\S+)$`)
+	enterMatch := fields.FindStringSubmatch(strings.Split(buff.String(), "\n")[0])
+	if enterMatch == nil {
+		t.Fatalf("Assertion failed! Expected entering line to contain callID, goroutine and time but got: %s", buff.String())
+	}
+	exitFields := regexp.MustCompile(`; callID=` + enterMatch[1] + `; goroutine=` + enterMatch[2] + `; time=\S+; duration=\S+$`)
+	if !exitFields.MatchString(strings.Split(buff.String(), "\n")[3]) {
+		t.Errorf("Assertion failed! Expected exiting line with the callID and goroutine of the entering line but got: %s", buff.String())
+	}
+}
+
+func TestEnterJSONFormat(t *testing.T) {
+	Configure(Config{Format: JSONFormat})
+	var buff bytes.Buffer
+	output = &buff
+	defer Configure(Config{})
+
+	_, _ = (&receiver{a: 1}).pointerMethod(2, "a;b c\n")
+
+	lines := strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Assertion failed! Expected 2 lines but got: %s", buff.String())
+	}
+	var enter, exit jsonEvent
+	if err := json.Unmarshal([]byte(lines[0]), &enter); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &exit); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedArgs := []formattedField{{Name: "i", Value: "2"}, {Name: "s...", Value: "[a;b c\n]"}}
+	if enter.Event != enterEvent || enter.Func != "github.com/DimitarPetrov/printracer/rt.(*receiver).pointerMethod" ||
+		enter.Receiver == nil || enter.Receiver.Name != "r" || !reflect.DeepEqual(enter.Args, expectedArgs) || len(enter.Duration) > 0 {
+		t.Errorf("Assertion failed! Unexpected entering event: %s", lines[0])
+	}
+	expectedResults := []formattedField{{Name: "res", Value: "3"}, {Name: "", Value: "<nil>"}}
+	if exit.Event != exitEvent || exit.Func != enter.Func || exit.Caller != enter.Caller || exit.CallID != enter.CallID ||
+		exit.Goroutine != enter.Goroutine || !reflect.DeepEqual(exit.Results, expectedResults) || len(exit.Duration) == 0 {
+		t.Errorf("Assertion failed! Unexpected exiting event: %s", lines[1])
+	}
+}
+
+func TestEnterWithConfigField(t *testing.T) {
+	configured := Configure(Config{Format: JSONFormat})
+	var buff bytes.Buffer
+	output = &buff
+	defer Configure(Config{})
+
+	func() {
+		defer Enter(configured, Arg("i", 1))()
+	}()
+
+	lines := strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Assertion failed! Expected 2 lines but got: %s", buff.String())
+	}
+	var enter jsonEvent
+	if err := json.Unmarshal([]byte(lines[0]), &enter); err != nil {
+		t.Fatal(err)
+	}
+	if expectedArgs := []formattedField{{Name: "i", Value: "1"}}; !reflect.DeepEqual(enter.Args, expectedArgs) {
+		t.Errorf("Assertion failed! Expected the config field to be ignored but got: %s", lines[0])
+	}
+}
+
+func TestEnterMaxArgLen(t *testing.T) {
+	Configure(Config{MaxArgLen: 4})
+	var buff bytes.Buffer
+	output = &buff
+	defer Configure(Config{})
+
+	_, _ = (&receiver{a: 1}).pointerMethod(123456, "héllo")
+
+	expected := []string{
+		`Entering function \S+ called by \S+ with receiver \(r=0x[0-9a-f]{2}\.\.\.\(\+\d+ bytes\)\) with args \(i=1234\.\.\.\(\+2 bytes\)\) \(s\.\.\.=\[hé\.\.\.\(\+4 bytes\)\); `,
+		`Exiting function \S+ called by \S+ with results \(res=1234\.\.\.\(\+2 bytes\)\) \(<nil\.\.\.\(\+1 bytes\)\); `,
+	}
+	lines := strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Assertion failed! Expected %d lines but got: %s", len(expected), buff.String())
+	}
+	for i, line := range lines {
+		if !regexp.MustCompile("^" + expected[i]).MatchString(line) {
+			t.Errorf("Assertion failed! Expected line matching %s but got: %s", expected[i], line)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		Value    string
+		MaxLen   int
+		Expected string
+	}{
+		{Value: "[1 2 3]", MaxLen: 0, Expected: "[1 2 3]"},
+		{Value: "[1 2 3]", MaxLen: 7, Expected: "[1 2 3]"},
+		{Value: "[1 2 3]", MaxLen: 3, Expected: "[1 ...(+4 bytes)"},
+		{Value: "aé", MaxLen: 2, Expected: "a...(+2 bytes)"},
+	}
+
+	for _, test := range tests {
+		if truncated := truncate(test.Value, test.MaxLen); truncated != test.Expected {
+			t.Errorf("Assertion failed! Expected %s got %s", test.Expected, truncated)
+		}
+	}
+}
+
+func TestMaxArgLenEnv(t *testing.T) {
+	defer func() {
+		maxArgLenEnvOnce = sync.Once{}
+		maxArgLenEnv = nil
+	}()
+
+	tests := []struct {
+		Env      string
+		Expected int
+	}{
+		{Env: "", Expected: 10},
+		{Env: "3", Expected: 3},
+		{Env: "0", Expected: 0},
+		{Env: "invalid", Expected: 10},
+	}
+
+	for _, test := range tests {
+		maxArgLenEnvOnce = sync.Once{}
+		maxArgLenEnv = nil
+		if err := os.Setenv(MaxArgLenEnv, test.Env); err != nil {
+			t.Fatal(err)
+		}
+		if maxLen := (Config{MaxArgLen: 10}).maxArgLen(); maxLen != test.Expected {
+			t.Errorf("Assertion failed! Expected %d got %d with %s=%s", test.Expected, maxLen, MaxArgLenEnv, test.Env)
+		}
+	}
+	if err := os.Unsetenv(MaxArgLenEnv); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,119 @@
+package rt
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// callStack tracks the traced calls in progress on a goroutine. Every call whose caller is traced is sampled on its own,
+// while the calls made within a call which is not sampled are skipped along with it, so that sampled traces are complete trees.
+type callStack struct {
+	depth int
+	// skippedDepth is the depth of the call in progress which is not sampled. Zero if all the calls in progress are.
+	skippedDepth int
+}
+
+// rateWindow counts the sampled calls of a function in a second.
+type rateWindow struct {
+	second int64
+	count  int
+}
+
+var (
+	samplingMutex sync.Mutex
+	// callStacks are the call stacks of the goroutines with traced calls in progress, by goroutine ID.
+	callStacks = make(map[string]*callStack)
+	// sampledCalls are the numbers of calls of the functions considered for sampling since the last (re)configuration.
+	// Functions are identified by the PC of their call to Enter, like in rateWindows.
+	sampledCalls = make(map[uintptr]int)
+	// rateWindows are the rate windows of the functions, identified by the PC of their call to Enter.
+	rateWindows = make(map[uintptr]*rateWindow)
+
+	// writtenEvents is the number of events written since the last (re)configuration.
+	writtenEvents uint64
+)
+
+// sampling reports whether any of the sampling modes is configured.
+func (c Config) sampling() bool {
+	return c.SampleRate > 1 || c.RateLimit > 0 || c.MaxEvents > 0
+}
+
+// sample decides whether the call of the instrumented function calling Enter on the goroutine is traced.
+// Calls made within a call which is not traced are not traced either, the others are sampled according to the configuration.
+// Returns a function to be called on the return of the call.
+func sample(c Config, goroutineID string) (bool, func()) {
+	if !c.sampling() {
+		return true, func() {}
+	}
+
+	samplingMutex.Lock()
+	defer samplingMutex.Unlock()
+	calls, ok := callStacks[goroutineID]
+	if !ok {
+		calls = &callStack{}
+		callStacks[goroutineID] = calls
+	}
+	calls.depth++
+	depth := calls.depth
+	sampled := calls.skippedDepth == 0 && sampleCall(c)
+	if !sampled && calls.skippedDepth == 0 {
+		calls.skippedDepth = depth
+	}
+
+	return sampled, func() {
+		samplingMutex.Lock()
+		defer samplingMutex.Unlock()
+		if calls.skippedDepth == depth {
+			calls.skippedDepth = 0
+		}
+		calls.depth--
+		if calls.depth == 0 {
+			delete(callStacks, goroutineID)
+		}
+	}
+}
+
+// sampleCall decides whether a call whose caller is traced is sampled. Must be called by sample with samplingMutex held.
+func sampleCall(c Config) bool {
+	if c.MaxEvents > 0 && atomic.LoadUint64(&writtenEvents) >= uint64(c.MaxEvents) {
+		return false
+	}
+
+	var pcs [1]uintptr
+	// Skip runtime.Callers, sampleCall, sample and Enter
+	runtime.Callers(4, pcs[:])
+	if c.SampleRate > 1 {
+		sampledCalls[pcs[0]]++
+		if (sampledCalls[pcs[0]]-1)%c.SampleRate != 0 {
+			return false
+		}
+	}
+
+	if c.RateLimit > 0 {
+		window, ok := rateWindows[pcs[0]]
+		if !ok {
+			window = &rateWindow{}
+			rateWindows[pcs[0]] = window
+		}
+		now := time.Now().Unix()
+		if window.second != now {
+			window.second, window.count = now, 0
+		}
+		if window.count >= c.RateLimit {
+			return false
+		}
+		window.count++
+	}
+	return true
+}
+
+// resetSampling starts the sampling anew, e.g. on (re)configuration. Calls in progress are not affected.
+func resetSampling() {
+	samplingMutex.Lock()
+	defer samplingMutex.Unlock()
+	sampledCalls = make(map[uintptr]int)
+	rateWindows = make(map[uintptr]*rateWindow)
+	atomic.StoreUint64(&writtenEvents, 0)
+}
@@ -0,0 +1,179 @@
+package rt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnterSampling(t *testing.T) {
+	tree := []string{
+		"Entering function github.com/DimitarPetrov/printracer/rt.traced ",
+		"Entering function github.com/DimitarPetrov/printracer/rt.traced.func1 ",
+		"Exiting function github.com/DimitarPetrov/printracer/rt.traced.func1 ",
+		"Exiting function github.com/DimitarPetrov/printracer/rt.traced ",
+	}
+	// untracedCallee is a call of traced whose call of traced.func1 is not sampled
+	untracedCallee := []string{
+		"Entering function github.com/DimitarPetrov/printracer/rt.traced ",
+		"Exiting function github.com/DimitarPetrov/printracer/rt.traced ",
+	}
+	redactedTree := []string{
+		"Entering function github.com/DimitarPetrov/printracer/rt.redacted ",
+		"Exiting function github.com/DimitarPetrov/printracer/rt.redacted ",
+	}
+
+	tests := []struct {
+		Name     string
+		Config   Config
+		Call     func()
+		Expected [][]string
+	}{
+		{
+			Name:     "NoSampling",
+			Config:   Config{SampleRate: 1},
+			Call:     func() { traced(); traced() },
+			Expected: [][]string{tree, tree},
+		},
+		{
+			Name:   "SampleRate",
+			Config: Config{SampleRate: 3},
+			Call: func() {
+				for i := 0; i < 7; i++ {
+					traced()
+				}
+			},
+			Expected: [][]string{tree, untracedCallee, untracedCallee},
+		},
+		{
+			Name:   "SampleRateWithinTracedCall",
+			Config: Config{SampleRate: 3},
+			Call: func() {
+				tracedLoop(7)
+			},
+			Expected: [][]string{{loopEnter}, tree, untracedCallee, untracedCallee, {loopExit}},
+		},
+		{
+			Name:   "RateLimit",
+			Config: Config{RateLimit: 2},
+			Call: func() {
+				for i := 0; i < 5; i++ {
+					traced()
+					redacted("john", "pass")
+				}
+			},
+			Expected: [][]string{tree, redactedTree, tree, redactedTree},
+		},
+		{
+			Name:   "MaxEvents",
+			Config: Config{MaxEvents: 5},
+			Call: func() {
+				for i := 0; i < 3; i++ {
+					traced()
+				}
+			},
+			Expected: [][]string{tree, untracedCallee[:1], untracedCallee[1:]},
+		},
+		{
+			Name:   "MaxEventsWithinTracedCall",
+			Config: Config{MaxEvents: 6},
+			Call: func() {
+				tracedLoop(10)
+			},
+			Expected: [][]string{{loopEnter}, tree, untracedCallee, {loopExit}},
+		},
+		{
+			Name:   "Combined",
+			Config: Config{SampleRate: 2, MaxEvents: 10},
+			Call: func() {
+				for i := 0; i < 10; i++ {
+					traced()
+				}
+			},
+			Expected: [][]string{tree, untracedCallee, tree},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			// The rate limit counts calls within a second, which should not end in the middle of the test
+			if now := time.Now(); now.Nanosecond() > int(900*time.Millisecond) {
+				time.Sleep(time.Second - time.Duration(now.Nanosecond()))
+			}
+			Configure(test.Config)
+			var buff bytes.Buffer
+			output = &buff
+			defer Configure(Config{})
+
+			test.Call()
+
+			var expected []string
+			for _, lines := range test.Expected {
+				expected = append(expected, lines...)
+			}
+			lines := strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+			if len(lines) != len(expected) {
+				t.Fatalf("Assertion failed! Expected %d lines but got: %s", len(expected), buff.String())
+			}
+			for i, line := range lines {
+				if !strings.HasPrefix(line, expected[i]) {
+					t.Errorf("Assertion failed! Expected line starting with %s but got: %s", expected[i], line)
+				}
+			}
+			if len(callStacks) != 0 {
+				t.Errorf("Assertion failed! Expected no call stacks after the calls returned but got %d", len(callStacks))
+			}
+		})
+	}
+}
+
+// tracedLoop is an instrumented function calling traced in a loop, like a long-lived call, e.g. main.
+func tracedLoop(n int) {
+	defer Enter(Arg("n", n))()
+	for i := 0; i < n; i++ {
+		traced()
+	}
+}
+
+const (
+	loopEnter = "Entering function github.com/DimitarPetrov/printracer/rt.tracedLoop "
+	loopExit  = "Exiting function github.com/DimitarPetrov/printracer/rt.tracedLoop "
+)
+
+func TestEnterSamplingSkipsCallsWithinUntracedCall(t *testing.T) {
+	Configure(Config{SampleRate: 2})
+	var buff bytes.Buffer
+	output = &buff
+	defer Configure(Config{})
+
+	for i := 0; i < 4; i++ {
+		func() {
+			defer Enter()()
+			traced()
+		}()
+	}
+
+	// The calls of the function literal are sampled 1 and 3, and the calls of traced made within them are sampled
+	// on their own, while the calls of traced made within the untraced calls 2 and 4 are skipped
+	root := "github.com/DimitarPetrov/printracer/rt.TestEnterSamplingSkipsCallsWithinUntracedCall.func1 "
+	expected := []string{
+		"Entering function " + root,
+		"Entering function github.com/DimitarPetrov/printracer/rt.traced ",
+		"Entering function github.com/DimitarPetrov/printracer/rt.traced.func1 ",
+		"Exiting function github.com/DimitarPetrov/printracer/rt.traced.func1 ",
+		"Exiting function github.com/DimitarPetrov/printracer/rt.traced ",
+		"Exiting function " + root,
+		"Entering function " + root,
+		"Exiting function " + root,
+	}
+	lines := strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Assertion failed! Expected %d lines but got: %s", len(expected), buff.String())
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, expected[i]) {
+			t.Errorf("Assertion failed! Expected line starting with %s but got: %s", expected[i], line)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package rt
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// EnabledEnv is the environment variable which disables the tracing of instrumented code at runtime, e.g. PRINTRACER_ENABLED=false.
+const EnabledEnv = "PRINTRACER_ENABLED"
+
+// FilterEnv is the environment variable holding a regular expression which limits the tracing at runtime to the functions
+// it matches. It is matched against the function names as printed in the trace, e.g. github.com/foo/bar.(*T).Baz.
+const FilterEnv = "PRINTRACER_FILTER"
+
+var (
+	// switchOnce guards reading of the environment, which happens when the first instrumented function is entered.
+	switchOnce sync.Once
+	enabled    bool
+	filter     *regexp.Regexp
+	// tracedFuncs caches whether the instrumented functions, identified by the PC of their call to Enter, match the filter.
+	tracedFuncs sync.Map
+)
+
+// loadSwitch reads the runtime switch and filter from the environment. Invalid values are reported and ignored.
+func loadSwitch() {
+	enabled = true
+	if env := os.Getenv(EnabledEnv); len(env) > 0 {
+		e, err := strconv.ParseBool(env)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "printracer: invalid %s %s, expected true or false\n", EnabledEnv, env)
+		} else {
+			enabled = e
+		}
+	}
+
+	filter = nil
+	if env := os.Getenv(FilterEnv); len(env) > 0 {
+		f, err := regexp.Compile(env)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "printracer: invalid %s %s, tracing all functions: %v\n", FilterEnv, env, err)
+		} else {
+			filter = f
+		}
+	}
+}
+
+// isTraced reports whether the instrumented function calling Enter is to be traced.
+func isTraced() bool {
+	switchOnce.Do(loadSwitch)
+	if !enabled {
+		return false
+	}
+	if filter == nil {
+		return true
+	}
+
+	var pcs [1]uintptr
+	// Skip runtime.Callers, isTraced and Enter
+	if runtime.Callers(3, pcs[:]) == 0 {
+		return true
+	}
+	if traced, ok := tracedFuncs.Load(pcs[0]); ok {
+		return traced.(bool)
+	}
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	traced := filter.MatchString(frame.Function)
+	tracedFuncs.Store(pcs[0], traced)
+	return traced
+}
+
+// skip is returned by Enter for functions which are not traced.
+func skip(...Field) {}
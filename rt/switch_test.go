@@ -0,0 +1,71 @@
+package rt
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEnterSwitch(t *testing.T) {
+	defer func() {
+		_ = os.Unsetenv(EnabledEnv)
+		_ = os.Unsetenv(FilterEnv)
+		switchOnce = sync.Once{}
+		tracedFuncs = sync.Map{}
+	}()
+
+	tests := []struct {
+		Name          string
+		Enabled       string
+		Filter        string
+		ExpectedFuncs []string
+	}{
+		{Name: "Default", ExpectedFuncs: []string{"rt.traced", "rt.traced.func1", "rt.traced.func1", "rt.traced"}},
+		{Name: "Enabled", Enabled: "true", ExpectedFuncs: []string{"rt.traced", "rt.traced.func1", "rt.traced.func1", "rt.traced"}},
+		{Name: "Disabled", Enabled: "false"},
+		{Name: "DisabledByZero", Enabled: "0"},
+		{Name: "InvalidEnabled", Enabled: "no way", ExpectedFuncs: []string{"rt.traced", "rt.traced.func1", "rt.traced.func1", "rt.traced"}},
+		{Name: "Filter", Filter: `rt\.traced$`, ExpectedFuncs: []string{"rt.traced", "rt.traced"}},
+		{Name: "FilterFunctionLiteral", Filter: `func1`, ExpectedFuncs: []string{"rt.traced.func1", "rt.traced.func1"}},
+		{Name: "FilterMatchingNothing", Filter: `^main\.`},
+		{Name: "DisabledWithFilter", Enabled: "false", Filter: `traced`},
+		{Name: "InvalidFilter", Filter: `traced(`, ExpectedFuncs: []string{"rt.traced", "rt.traced.func1", "rt.traced.func1", "rt.traced"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if err := os.Setenv(EnabledEnv, test.Enabled); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Setenv(FilterEnv, test.Filter); err != nil {
+				t.Fatal(err)
+			}
+			switchOnce = sync.Once{}
+			tracedFuncs = sync.Map{}
+			var buff bytes.Buffer
+			output = &buff
+			defer func() {
+				output = nil
+			}()
+
+			traced()
+			traced()
+
+			var lines []string
+			if buff.Len() > 0 {
+				lines = strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+			}
+			if len(lines) != 2*len(test.ExpectedFuncs) {
+				t.Fatalf("Assertion failed! Expected %d lines but got: %s", 2*len(test.ExpectedFuncs), buff.String())
+			}
+			for i, line := range lines {
+				expected := "function github.com/DimitarPetrov/printracer/" + test.ExpectedFuncs[i%len(test.ExpectedFuncs)] + " called by "
+				if !strings.Contains(line, expected) {
+					t.Errorf("Assertion failed! Expected line containing %s but got: %s", expected, line)
+				}
+			}
+		})
+	}
+}
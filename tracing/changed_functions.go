@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"fmt"
+	"github.com/dave/dst/decorator"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+)
+
+// ChangedFunctions returns the names of the functions instrumented and reverted by the change, e.g. foo or T.String.
+func ChangedFunctions(change FileChange) ([]string, []string, error) {
+	before, err := instrumentedFunctionNames(change.Path, change.Original)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := instrumentedFunctionNames(change.Path, change.Content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return difference(after, before), difference(before, after), nil
+}
+
+func instrumentedFunctionNames(fileName string, src []byte) ([]string, error) {
+	if src == nil || filepath.Ext(fileName) != ".go" {
+		return nil, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing go file %s: %v", fileName, err)
+	}
+	f, err := decorator.DecorateFile(fset, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting file from ast to dst: %v", err)
+	}
+	var names []string
+	for _, fn := range collectFunctions(f, "") {
+		if hasInstrumentationWatermark(fn.body) {
+			names = append(names, fn.displayName())
+		}
+	}
+	return names, nil
+}
+
+// difference returns the elements of a which are not in b.
+func difference(a, b []string) []string {
+	set := make(map[string]bool, len(b))
+	for _, s := range b {
+		set[s] = true
+	}
+	var result []string
+	for _, s := range a {
+		if !set[s] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
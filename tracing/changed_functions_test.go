@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangedFunctions(t *testing.T) {
+	tests := []struct {
+		Name                 string
+		Change               FileChange
+		ExpectedInstrumented []string
+		ExpectedReverted     []string
+	}{
+		{
+			Name:                 "Instrumentation",
+			Change:               FileChange{Path: "main.go", Original: []byte(codeWithMethods), Content: []byte(resultCodeWithExcludedMethods)},
+			ExpectedInstrumented: []string{"T.pointer"},
+		},
+		{
+			Name:             "Deinstrumentation",
+			Change:           FileChange{Path: "main.go", Original: []byte(resultCodeWithFuncLits), Content: []byte(codeWithFuncLits)},
+			ExpectedReverted: []string{"init.func1", "test", "test.func1", "test.func2", "test.func2.1"},
+		},
+		{
+			Name:   "NotGoFile",
+			Change: FileChange{Path: "README.md", Content: []byte("func main() {}")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			instrumented, reverted, err := ChangedFunctions(test.Change)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(instrumented, test.ExpectedInstrumented) || !reflect.DeepEqual(reverted, test.ExpectedReverted) {
+				t.Errorf("Assertion failed! Got instrumented %v and reverted %v", instrumented, reverted)
+			}
+		})
+	}
+}
@@ -1,30 +1,32 @@
 package tracing
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/dave/dst"
 	"github.com/dave/dst/decorator"
 	"go/ast"
-	"go/parser"
 	"go/token"
 	"io"
-	"os"
+	"path/filepath"
 	"reflect"
 )
 
 type codeDeinstrumenter struct {
+	store FileStore
 }
 
-func NewCodeDeinstrumenter() CodeDeinstrumenter {
-	return &codeDeinstrumenter{}
+func NewCodeDeinstrumenter(store FileStore) CodeDeinstrumenter {
+	return &codeDeinstrumenter{store: store}
 }
 
 func (cd *codeDeinstrumenter) DeinstrumentDirectory(path string) error {
 	fset := token.NewFileSet()
-	filter := func(info os.FileInfo) bool {
-		return testsFilter(info) && generatedFilter(path, info)
+	// Test and generated files are reverted as well, as they might be instrumented
+	filter := func(fileName string, src []byte) bool {
+		return runtimeConfigFilter(src)
 	}
-	pkgs, err := parser.ParseDir(fset, path, filter, parser.ParseComments)
+	pkgs, err := parseDir(fset, cd.store, path, filter)
 	if err != nil {
 		return fmt.Errorf("failed parsing go files in directory %s: %v", path, err)
 	}
@@ -37,17 +39,24 @@ func (cd *codeDeinstrumenter) DeinstrumentDirectory(path string) error {
 	return nil
 }
 
+// DeinstrumentPackage deinstruments the files of the package, which might be a subset of the files in the directory.
+// The runtime configuration file generated in the directory is removed once no file in the directory refers to it.
 func (cd *codeDeinstrumenter) DeinstrumentPackage(fset *token.FileSet, pkg *ast.Package) error {
+	var dir string
 	for fileName, file := range pkg.Files {
-		sourceFile, err := os.OpenFile(fileName, os.O_TRUNC|os.O_WRONLY, 0664)
-		if err != nil {
-			return fmt.Errorf("failed opening file %s: %v", fileName, err)
-		}
-		if err := cd.DeinstrumentFile(fset, file, sourceFile); err != nil {
+		dir = filepath.Dir(fileName)
+		var buff bytes.Buffer
+		if err := cd.DeinstrumentFile(fset, file, &buff); err != nil {
 			return fmt.Errorf("failed deinstrumenting file %s: %v", fileName, err)
 		}
+		if err := cd.store.WriteFile(fileName, buff.Bytes()); err != nil {
+			return fmt.Errorf("failed writing file %s: %v", fileName, err)
+		}
 	}
-	return nil
+	if len(dir) == 0 {
+		return nil
+	}
+	return removeRuntimeConfigFile(cd.store, dir)
 }
 
 func (cd *codeDeinstrumenter) DeinstrumentFile(fset *token.FileSet, file *ast.File, out io.Writer) error {
@@ -57,33 +66,71 @@ func (cd *codeDeinstrumenter) DeinstrumentFile(fset *token.FileSet, file *ast.Fi
 	if err != nil {
 		return fmt.Errorf("failed converting file from ast to dst: %v", err)
 	}
-	dst.Inspect(f, func(n dst.Node) bool {
-		switch t := n.(type) {
-		case *dst.FuncDecl:
-			if len(t.Body.List) >= instrumentationStmtsCount {
-				firstStmntDecorations := t.Body.List[0].Decorations().Start.All()
-				secondStmntDecorations := t.Body.List[instrumentationStmtsCount-1].Decorations().End.All()
-				if len(firstStmntDecorations) > 0 && firstStmntDecorations[0] == printracerCommentWatermark &&
-					len(secondStmntDecorations) > 0 && secondStmntDecorations[0] == printracerCommentWatermark {
-
-					if checkInstrumentationStatementsIntegrity(t) {
-						t.Body.List = t.Body.List[instrumentationStmtsCount:]
-						if len(t.Body.List) > 0 {
-							t.Body.List[0].Decorations().Before = dst.None
-						}
-					}
-				}
+	rtImport := importName(file, rtPackagePath)
+	for _, fn := range collectFunctions(f, importName(file, "testing")) {
+		if hasInstrumentationWatermarks(fn, instrumentationStmtsCount) {
+			if fn.isLiteral() {
+				fn.position = instrumentedFuncLitPosition(fn.body.List[0])
 			}
+			fn.rtImport = rtImport
+			fn.configVar = instrumentedConfigVar(fn.body.List[0])
+			fn.generatedResults = instrumentedGeneratedResults(fn.body.List[0])
+			fn.typedArgs = instrumentedTypedArgs(fn.body.List[0])
+			fn.redacted = instrumentedRedactedNames(fn.body.List[0])
+			if checkInstrumentationStatementsIntegrity(fn) {
+				unnameResults(fn)
+				removeInstrumentationStmts(fn, instrumentationStmtsCount)
+			}
+		} else if hasInstrumentationWatermarks(fn, legacyInstrumentationStmtsCount) && checkLegacyInstrumentationStatementsIntegrity(fn) {
+			removeInstrumentationStmts(fn, legacyInstrumentationStmtsCount)
 		}
-		return true
-	})
+	}
 
 	return decorator.Fprint(out, f)
 }
 
-func checkInstrumentationStatementsIntegrity(f *dst.FuncDecl) bool {
-	stmts := f.Body.List
-	instrumentationStmts := buildInstrumentationStmts(f)
+// hasInstrumentationWatermarks reports whether the function starts with count statements enclosed in watermarks.
+func hasInstrumentationWatermarks(f *function, count int) bool {
+	if len(f.body.List) < count {
+		return false
+	}
+	firstStmntDecorations := f.body.List[0].Decorations().Start.All()
+	lastStmntDecorations := f.body.List[count-1].Decorations().End.All()
+	return len(firstStmntDecorations) > 0 && firstStmntDecorations[0] == printracerCommentWatermark &&
+		len(lastStmntDecorations) > 0 && lastStmntDecorations[0] == printracerCommentWatermark
+}
+
+// removeInstrumentationStmts removes the instrumentation statements and restores the layout of the body. Bodies whose
+// first statement directly follows the instrumentation, without an empty line, were on a single line, see instrumentFile.
+func removeInstrumentationStmts(f *function, count int) {
+	f.body.List = f.body.List[count:]
+	if len(f.body.List) == 0 {
+		return
+	}
+	if f.body.List[0].Decorations().Before != dst.NewLine {
+		f.body.List[0].Decorations().Before = dst.NewLine
+		return
+	}
+	for _, stmt := range f.body.List {
+		stmt.Decorations().Before = dst.None
+		stmt.Decorations().After = dst.None
+	}
+}
+
+// checkInstrumentationStatementsIntegrity reports whether the function starts with unmodified instrumentation statements
+// built with any of the supported options.
+func checkInstrumentationStatementsIntegrity(f *function) bool {
+	for _, opts := range []Options{{}, {PrintReceivers: true}} {
+		if checkInstrumentationStatementsIntegrityWithOptions(f, opts) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkInstrumentationStatementsIntegrityWithOptions(f *function, opts Options) bool {
+	stmts := f.body.List
+	instrumentationStmts := buildInstrumentationStmts(f, opts)
 
 	for i := 0; i < instrumentationStmtsCount; i++ {
 		if !equalStmt(stmts[i], instrumentationStmts[i]) {
@@ -93,6 +140,21 @@ func checkInstrumentationStatementsIntegrity(f *dst.FuncDecl) bool {
 	return true
 }
 
+// checkLegacyInstrumentationStatementsIntegrity reports whether the function starts with unmodified instrumentation
+// statements of former versions, see buildLegacyInstrumentationStmts.
+func checkLegacyInstrumentationStatementsIntegrity(f *function) bool {
+	instrumentationStmts, ok := buildLegacyInstrumentationStmts(f)
+	if !ok {
+		return false
+	}
+	for i := 0; i < legacyInstrumentationStmtsCount; i++ {
+		if !equalStmt(f.body.List[i], instrumentationStmts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func equalStmt(stmt1, stmt2 dst.Stmt) bool {
 	switch t := stmt1.(type) {
 	case *dst.AssignStmt:
@@ -200,6 +262,12 @@ func equalExpr(expr1, expr2 dst.Expr) bool {
 			return false
 		}
 		return true
+	case *dst.UnaryExpr:
+		instExpr, ok := expr2.(*dst.UnaryExpr)
+		if !ok {
+			return false
+		}
+		return t.Op == instExpr.Op && equalExpr(t.X, instExpr.X)
 	case *dst.BasicLit:
 		instExpr, ok := expr2.(*dst.BasicLit)
 		if !ok {
@@ -3,6 +3,7 @@ package tracing
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
 	"go/parser"
 	"go/token"
 	"io/ioutil"
@@ -18,9 +19,24 @@ func TestDeinstrumentFile(t *testing.T) {
 	}{
 		{Name: "DeinstrumentFileWithoutImports", InputCode: resultCodeWithoutImports, OutputCode: codeWithoutImports},
 		{Name: "DeinstrumentFileWithFmtImportOnly", InputCode: resultCodeWithFmtImport, OutputCode: codeWithFmtImport},
+		{Name: "DeinstrumentFileWithSingleImport", InputCode: resultCodeWithSingleImport, OutputCode: codeWithSingleImport},
+		{Name: "DeinstrumentFileWithSingleImportAndCollidingName", InputCode: resultCodeWithSingleImportAndCollidingName, OutputCode: codeWithSingleImportAndCollidingName},
 		{Name: "DeinstrumentFileWithMultipleImports", InputCode: resultCodeWithMultipleImports, OutputCode: codeWithMultipleImports},
 		{Name: "DeinstrumentFileWithoutFmtImport", InputCode: resultCodeWithImportsWithoutFmt, OutputCode: codeWithImportsWithoutFmt},
 		{Name: "DeinstrumentFileWithoutFunctions", InputCode: resultCodeWithoutFunction, OutputCode: codeWithoutFunction},
+		{Name: "DeinstrumentFileWithFunctionLiterals", InputCode: resultCodeWithFuncLits, OutputCode: codeWithFuncLits},
+		{Name: "DeinstrumentFileWithOneLineBodies", InputCode: resultCodeWithOneLineBodies, OutputCode: codeWithOneLineBodies},
+		{Name: "DeinstrumentFileWithResults", InputCode: resultCodeWithResults, OutputCode: codeWithResults},
+		{Name: "DeinstrumentFileWithDifferentKindsOfParams", InputCode: resultCodeWithDifferentKindsOfParams, OutputCode: codeWithDifferentKindsOfParams},
+		{Name: "DeinstrumentFileWithReceivers", InputCode: resultCodeWithMethods, OutputCode: codeWithMethods},
+		{Name: "DeinstrumentFileWithTests", InputCode: resultCodeWithTests, OutputCode: codeWithTests},
+		{Name: "DeinstrumentFileWithCollidingNames", InputCode: resultCodeWithCollidingNames, OutputCode: codeWithCollidingNames},
+		{Name: "DeinstrumentFileReusingRtImport", InputCode: resultCodeWithRtImports, OutputCode: codeWithRtImports},
+		{Name: "DeinstrumentFileWithBlankRtImport", InputCode: resultCodeWithBlankRtImport, OutputCode: codeWithBlankRtImport},
+		{Name: "DeinstrumentFileWithTypes", InputCode: resultCodeWithTypes, OutputCode: codeWithTypes},
+		{Name: "DeinstrumentFileWithSensitiveParams", InputCode: resultCodeWithSensitiveParams, OutputCode: codeWithSensitiveParams},
+		{Name: "DeinstrumentFileWithLegacyInstrumentation", InputCode: legacyResultCodeWithoutImports, OutputCode: codeWithoutImports},
+		{Name: "DeinstrumentFileWithLegacyInstrumentationAndMultipleImports", InputCode: legacyResultCodeWithMultipleImports, OutputCode: codeWithMultipleImports},
 		{Name: "DeinstrumentFileWithoutPreviousInstrumentation", InputCode: codeWithMultipleImports, OutputCode: codeWithMultipleImports},
 		{Name: "DeinstrumentFileDoesNotChangeManuallyEditedFunctions", InputCode: editedResultCodeWithoutImports, OutputCode: editedResultCodeWithoutImports},
 	}
@@ -33,7 +49,7 @@ func TestDeinstrumentFile(t *testing.T) {
 				t.Fatal(err)
 			}
 			var buff bytes.Buffer
-			if err := NewCodeDeinstrumenter().DeinstrumentFile(fset, file, &buff); err != nil {
+			if err := NewCodeDeinstrumenter(NewFileStore()).DeinstrumentFile(fset, file, &buff); err != nil {
 				t.Fatal(err)
 			}
 
@@ -43,7 +59,7 @@ func TestDeinstrumentFile(t *testing.T) {
 			}
 
 			var buff2 bytes.Buffer
-			if err := NewImportsGroomer().RemoveUnusedImportFromFile(fset, file, &buff2, map[string]string{"fmt": "", "runtime": "rt", "crypto/rand": ""}); err != nil {
+			if err := NewImportsGroomer(NewFileStore()).RemoveUnusedImportFromFile(fset, file, &buff2, InstrumentationImports()); err != nil {
 				t.Fatal(err)
 			}
 
@@ -84,11 +100,14 @@ func TestDeinstrumentDirectory(t *testing.T) {
 		i++
 	}
 
-	if err := NewCodeDeinstrumenter().DeinstrumentDirectory("test"); err != nil {
+	store := NewFileStore()
+	if err := NewCodeDeinstrumenter(store).DeinstrumentDirectory("test"); err != nil {
 		t.Fatal(err)
 	}
-
-	if err := NewImportsGroomer().RemoveUnusedImportFromDirectory("test", map[string]string{"fmt": "", "runtime": "rt", "crypto/rand": ""}); err != nil {
+	if err := NewImportsGroomer(store).RemoveUnusedImportFromDirectory("test", map[string]string{rtPackagePath: rtPackageAlias}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(); err != nil {
 		t.Fatal(err)
 	}
 
@@ -104,3 +123,220 @@ func TestDeinstrumentDirectory(t *testing.T) {
 		i++
 	}
 }
+
+func TestDeinstrumentDirectoryRemovesRuntimeConfig(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	files := map[string]string{
+		"test.go":             resultCodeWithoutImports,
+		runtimeConfigFileName: runtimeConfigWithJSONFormat,
+		"other_config.go":     runtimeConfigWithJSONFormat,
+	}
+	for name, code := range files {
+		if err := ioutil.WriteFile("test/"+name, []byte(code), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewFileStore()
+	if err := NewCodeDeinstrumenter(store).DeinstrumentDirectory("test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat("test/" + runtimeConfigFileName); !os.IsNotExist(err) {
+		t.Error("Assertion failed! Expected runtime config to be removed")
+	}
+	if _, err := os.Stat("test/other_config.go"); err != nil {
+		t.Error("Assertion failed! Expected files other than the runtime config to be preserved")
+	}
+}
+
+func TestDeinstrumentDirectoryRevertsTests(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err := ioutil.WriteFile("test/test_test.go", []byte(resultCodeWithTests), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewFileStore()
+	if err := NewCodeDeinstrumenter(store).DeinstrumentDirectory("test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewImportsGroomer(store).RemoveUnusedImportFromDirectory("test", map[string]string{rtPackagePath: rtPackageAlias}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := store.ReadFile("test/test_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != codeWithTests {
+		t.Errorf("Assertion failed! Expected %s got %s", codeWithTests, string(data))
+	}
+}
+
+func TestDeinstrumentPackageRemovesRuntimeConfigOnceUnused(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for _, name := range []string{"test/a.go", "test/b.go"} {
+		if err := ioutil.WriteFile(name, []byte(codeWithoutImports), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := NewFileStore()
+	if err := NewCodeInstrumenter(store).InstrumentDirectory("test", Options{Format: JSONFormat}); err != nil {
+		t.Fatal(err)
+	}
+
+	deinstrumenter := NewCodeDeinstrumenter(store)
+	for i, name := range []string{"test/a.go", "test/b.go"} {
+		src, err := store.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := deinstrumenter.DeinstrumentPackage(fset, &ast.Package{Name: file.Name.Name, Files: map[string]*ast.File{name: file}}); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = store.ReadFile("test/" + runtimeConfigFileName)
+		if expectedRemoved := i == 1; os.IsNotExist(err) != expectedRemoved {
+			t.Errorf("Assertion failed! Expected runtime config removed to be %t after reverting %s", expectedRemoved, name)
+		}
+	}
+}
+
+// Code instrumented by former versions, which printed the trace with fmt directly from the instrumented functions.
+const legacyResultCodeWithoutImports = `package a
+
+import (
+	"crypto/rand"
+	"fmt"
+	rt "runtime"
+)
+
+func test(i int, b bool) int {
+
+	/* prinTracer */
+	funcName := "test"
+	caller := "unknown"
+	if funcPC, _, _, ok := rt.Caller(0); ok {
+		funcName = rt.FuncForPC(funcPC).Name()
+	}
+	if callerPC, _, _, ok := rt.Caller(1); ok {
+		caller = rt.FuncForPC(callerPC).Name()
+	}
+	idBytes := make([]byte, 16)
+	_, _ = rand.Read(idBytes)
+	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
+	fmt.Printf("Entering function %s called by %s with args (%v) (%v); callID=%s\n", funcName, caller, i, b, callID)
+	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+
+	if b {
+		return i
+	}
+	return 0
+}
+
+func main() {
+
+	/* prinTracer */
+	funcName := "main"
+	caller := "unknown"
+	if funcPC, _, _, ok := rt.Caller(0); ok {
+		funcName = rt.FuncForPC(funcPC).Name()
+	}
+	if callerPC, _, _, ok := rt.Caller(1); ok {
+		caller = rt.FuncForPC(callerPC).Name()
+	}
+	idBytes := make([]byte, 16)
+	_, _ = rand.Read(idBytes)
+	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
+	fmt.Printf("Entering function %s called by %s; callID=%s\n", funcName, caller, callID)
+	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+
+	i := test(2, false)
+}
+`
+
+const legacyResultCodeWithMultipleImports = `package a
+
+import (
+	"crypto/rand"
+	"fmt"
+	rt "runtime"
+	"strconv"
+)
+
+func test(i int, b bool) int {
+
+	/* prinTracer */
+	funcName := "test"
+	caller := "unknown"
+	if funcPC, _, _, ok := rt.Caller(0); ok {
+		funcName = rt.FuncForPC(funcPC).Name()
+	}
+	if callerPC, _, _, ok := rt.Caller(1); ok {
+		caller = rt.FuncForPC(callerPC).Name()
+	}
+	idBytes := make([]byte, 16)
+	_, _ = rand.Read(idBytes)
+	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
+	fmt.Printf("Entering function %s called by %s with args (%v) (%v); callID=%s\n", funcName, caller, i, b, callID)
+	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+
+	if b {
+		return i
+	}
+	return 0
+}
+
+func main() {
+
+	/* prinTracer */
+	funcName := "main"
+	caller := "unknown"
+	if funcPC, _, _, ok := rt.Caller(0); ok {
+		funcName = rt.FuncForPC(funcPC).Name()
+	}
+	if callerPC, _, _, ok := rt.Caller(1); ok {
+		caller = rt.FuncForPC(callerPC).Name()
+	}
+	idBytes := make([]byte, 16)
+	_, _ = rand.Read(idBytes)
+	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
+	fmt.Printf("Entering function %s called by %s; callID=%s\n", funcName, caller, callID)
+	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+
+	i := test(2, false)
+	fmt.Println(strconv.Itoa(i))
+}
+`
@@ -0,0 +1,227 @@
+package tracing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileChange is a change of a single file recorded by a FileStore.
+type FileChange struct {
+	Path string
+	// Original is the content of the file on disk. Nil if the file is created by the change.
+	Original []byte
+	// Content is the new content of the file. Nil if the file is removed by the change.
+	Content []byte
+}
+
+// fileStore keeps the files written and removed in memory on top of the files on disk until they are committed.
+type fileStore struct {
+	files map[string][]byte // Nil content stands for a removed file
+}
+
+func NewFileStore() FileStore {
+	return &fileStore{files: make(map[string][]byte)}
+}
+
+func (fs *fileStore) ReadFile(path string) ([]byte, error) {
+	content, ok := fs.files[filepath.Clean(path)]
+	if !ok {
+		return ioutil.ReadFile(path)
+	}
+	if content == nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return append([]byte(nil), content...), nil
+}
+
+func (fs *fileStore) WriteFile(path string, content []byte) error {
+	fs.files[filepath.Clean(path)] = append([]byte{}, content...)
+	return nil
+}
+
+func (fs *fileStore) RemoveFile(path string) error {
+	if _, err := fs.ReadFile(path); err != nil {
+		return err
+	}
+	fs.files[filepath.Clean(path)] = nil
+	return nil
+}
+
+func (fs *fileStore) Changes() ([]FileChange, error) {
+	paths := make([]string, 0, len(fs.files))
+	for path := range fs.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var changes []FileChange
+	for _, path := range paths {
+		original, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed reading file %s: %v", path, err)
+		}
+		content := fs.files[path]
+		if (original == nil) == (content == nil) && bytes.Equal(original, content) {
+			continue
+		}
+		changes = append(changes, FileChange{Path: path, Original: original, Content: content})
+	}
+	return changes, nil
+}
+
+func (fs *fileStore) Commit() error {
+	changes, err := fs.Changes()
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if change.Content == nil {
+			if err := os.Remove(change.Path); err != nil {
+				return fmt.Errorf("failed removing file %s: %v", change.Path, err)
+			}
+			continue
+		}
+		if err := ioutil.WriteFile(change.Path, change.Content, 0664); err != nil {
+			return fmt.Errorf("failed writing file %s: %v", change.Path, err)
+		}
+	}
+	fs.Discard()
+	return nil
+}
+
+func (fs *fileStore) WriteOverlay(dir string) (string, error) {
+	changes, err := fs.Changes()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return "", fmt.Errorf("failed creating overlay directory %s: %v", dir, err)
+	}
+	overlayFile := filepath.Join(dir, overlayFileName)
+	if err := removePreviousOverlay(overlayFile); err != nil {
+		return "", err
+	}
+
+	o := overlay{Replace: make(map[string]string, len(changes))}
+	for _, change := range changes {
+		path, err := filepath.Abs(change.Path)
+		if err != nil {
+			return "", fmt.Errorf("failed resolving absolute path of %s: %v", change.Path, err)
+		}
+		if change.Content == nil { // Empty replacement stands for a removed file
+			o.Replace[path] = ""
+			continue
+		}
+		copyPath := filepath.Join(dir, overlayCopyName(path))
+		if err := ioutil.WriteFile(copyPath, change.Content, 0664); err != nil {
+			return "", fmt.Errorf("failed writing file %s: %v", copyPath, err)
+		}
+		if o.Replace[path], err = filepath.Abs(copyPath); err != nil {
+			return "", fmt.Errorf("failed resolving absolute path of %s: %v", copyPath, err)
+		}
+	}
+
+	data, err := json.MarshalIndent(o, "", "\t")
+	if err != nil {
+		return "", fmt.Errorf("failed marshaling overlay: %v", err)
+	}
+	if err := ioutil.WriteFile(overlayFile, data, 0664); err != nil {
+		return "", fmt.Errorf("failed writing file %s: %v", overlayFile, err)
+	}
+	fs.Discard()
+	return overlayFile, nil
+}
+
+func (fs *fileStore) Discard() {
+	fs.files = make(map[string][]byte)
+}
+
+// Name of the overlay file written by WriteOverlay in the overlay directory.
+const overlayFileName = "overlay.json"
+
+// overlay is the format of the file accepted by the -overlay flag of go build and go test.
+type overlay struct {
+	Replace map[string]string
+}
+
+// overlayCopyName returns the name of the copy of the file in the overlay directory.
+// The name is unique per path, while keeping the base name of the file for readable compilation errors.
+func overlayCopyName(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return fmt.Sprintf("%x-%s", sum[:8], filepath.Base(path))
+}
+
+// removePreviousOverlay removes the copies referred by the overlay file written by a previous WriteOverlay if any.
+func removePreviousOverlay(overlayFile string) error {
+	data, err := ioutil.ReadFile(overlayFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed reading file %s: %v", overlayFile, err)
+	}
+	var previous overlay
+	if err := json.Unmarshal(data, &previous); err != nil {
+		return fmt.Errorf("failed parsing overlay file %s: %v", overlayFile, err)
+	}
+	dir, err := filepath.Abs(filepath.Dir(overlayFile))
+	if err != nil {
+		return fmt.Errorf("failed resolving absolute path of %s: %v", overlayFile, err)
+	}
+	for _, copyPath := range previous.Replace {
+		if len(copyPath) == 0 || filepath.Dir(copyPath) != dir { // Not written by WriteOverlay
+			continue
+		}
+		if err := os.Remove(copyPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed removing file %s: %v", copyPath, err)
+		}
+	}
+	return nil
+}
+
+// parseDir is like parser.ParseDir but reads the content of the files from the store.
+// Files are parsed only if the filter accepts their names and content.
+func parseDir(fset *token.FileSet, store FileStore, path string, filter func(fileName string, src []byte) bool) (map[string]*ast.Package, error) {
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := make(map[string]*ast.Package)
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".go" {
+			continue
+		}
+		fileName := filepath.Join(path, info.Name())
+		src, err := store.ReadFile(fileName)
+		if os.IsNotExist(err) && info.Mode().IsRegular() { // Removed in the store
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed reading file %s: %v", fileName, err)
+		}
+		if !filter(info.Name(), src) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, fileName, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		pkg, ok := pkgs[file.Name.Name]
+		if !ok {
+			pkg = &ast.Package{Name: file.Name.Name, Files: make(map[string]*ast.File)}
+			pkgs[file.Name.Name] = pkg
+		}
+		pkg.Files[fileName] = file
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for name, content := range map[string]string{"test/modified.go": "a", "test/removed.go": "b", "test/unchanged.go": "c"} {
+		if err := ioutil.WriteFile(name, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewFileStore()
+	if err := store.WriteFile("test/modified.go", []byte("A")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveFile("test/removed.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.WriteFile("test/created.go", []byte("D")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.WriteFile("test/unchanged.go", []byte("c")); err != nil {
+		t.Fatal(err)
+	}
+
+	if content, err := store.ReadFile("test/modified.go"); err != nil || string(content) != "A" {
+		t.Errorf("Assertion failed! Expected content of the store but got %s", string(content))
+	}
+	if _, err := store.ReadFile("test/removed.go"); !os.IsNotExist(err) {
+		t.Error("Assertion failed! Expected removed file not to exist")
+	}
+	if data, err := ioutil.ReadFile("test/modified.go"); err != nil || string(data) != "a" {
+		t.Error("Assertion failed! Expected file on disk to be untouched before commit")
+	}
+
+	changes, err := store.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []FileChange{
+		{Path: "test/created.go", Content: []byte("D")},
+		{Path: "test/modified.go", Original: []byte("a"), Content: []byte("A")},
+		{Path: "test/removed.go", Original: []byte("b")},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Assertion failed! Expected %v got %v", expected, changes)
+	}
+
+	if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile("test/modified.go"); err != nil || string(data) != "A" {
+		t.Error("Assertion failed! Expected file on disk to be modified after commit")
+	}
+	if data, err := ioutil.ReadFile("test/created.go"); err != nil || string(data) != "D" {
+		t.Error("Assertion failed! Expected file on disk to be created after commit")
+	}
+	if _, err := os.Stat("test/removed.go"); !os.IsNotExist(err) {
+		t.Error("Assertion failed! Expected file on disk to be removed after commit")
+	}
+	if changes, err := store.Changes(); err != nil || len(changes) != 0 {
+		t.Errorf("Assertion failed! Expected no changes after commit but got %v", changes)
+	}
+}
+
+func TestFileStoreWriteOverlay(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err := ioutil.WriteFile("test/removed.go", []byte("b"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	store := NewFileStore()
+	if err := store.WriteFile("test/created.go", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveFile("test/removed.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	overlayFile, err := store.WriteOverlay("test/overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("test/created.go"); !os.IsNotExist(err) {
+		t.Error("Assertion failed! Expected working tree not to be modified")
+	}
+	if _, err := os.Stat("test/removed.go"); err != nil {
+		t.Error("Assertion failed! Expected working tree not to be modified")
+	}
+	if changes, err := store.Changes(); err != nil || len(changes) != 0 {
+		t.Errorf("Assertion failed! Expected changes to be discarded but got %v", changes)
+	}
+
+	data, err := ioutil.ReadFile(overlayFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var o overlay
+	if err := json.Unmarshal(data, &o); err != nil {
+		t.Fatal(err)
+	}
+	created, _ := filepath.Abs("test/created.go")
+	removed, _ := filepath.Abs("test/removed.go")
+	if len(o.Replace) != 2 || o.Replace[removed] != "" {
+		t.Fatalf("Assertion failed! Unexpected overlay %s", string(data))
+	}
+	if copyData, err := ioutil.ReadFile(o.Replace[created]); err != nil || string(copyData) != "a" {
+		t.Errorf("Assertion failed! Expected copy of the created file in the overlay")
+	}
+
+	// Copies of a previous overlay are removed
+	if _, err := store.WriteOverlay("test/overlay"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(o.Replace[created]); !os.IsNotExist(err) {
+		t.Error("Assertion failed! Expected copy of the previous overlay to be removed")
+	}
+}
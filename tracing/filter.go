@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Patterns prefixed with regexPatternPrefix are regular expressions, all the others are globs.
+const regexPatternPrefix = "re:"
+
+// functionFilter decides which functions get instrumented based on the include and exclude patterns of the options.
+// Patterns are matched against the name of the function (e.g. foo, T.String, main.T.String or
+// github.com/foo/bar/internal/baz.T.String), against the import path of its package (e.g. github.com/foo/bar/internal/baz)
+// and against the path of its file (e.g. internal/generated/types.go).
+type functionFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	// importPaths caches the import paths of the packages by directory. Empty for directories outside of a module.
+	importPaths map[string]string
+}
+
+func newFunctionFilter(opts Options) (*functionFilter, error) {
+	include, err := compilePatterns(opts.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compilePatterns(opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	return &functionFilter{include: include, exclude: exclude, importPaths: make(map[string]string)}, nil
+}
+
+// accepts reports whether the function should be instrumented.
+// A function is instrumented if it matches any of the include patterns (if any) and none of the exclude patterns.
+func (ff *functionFilter) accepts(fileName, pkgName string, f *function) bool {
+	if len(ff.include) == 0 && len(ff.exclude) == 0 {
+		return true
+	}
+	candidates := append(functionNameCandidates(ff.importPath(fileName, pkgName), pkgName, f), pathCandidates(fileName)...)
+	if len(ff.include) > 0 && !matchesAny(ff.include, candidates) {
+		return false
+	}
+	return !matchesAny(ff.exclude, candidates)
+}
+
+func matchesAny(patterns []*regexp.Regexp, candidates []string) bool {
+	for _, pattern := range patterns {
+		for _, candidate := range candidates {
+			if pattern.MatchString(candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// importPath returns the import path of the package of the file. External test packages are suffixed with _test
+// the way the go runtime names their functions, e.g. github.com/foo/bar_test. Empty if the file is not in a module.
+func (ff *functionFilter) importPath(fileName, pkgName string) string {
+	if len(fileName) == 0 {
+		return ""
+	}
+	dir := filepath.Dir(fileName)
+	path, ok := ff.importPaths[dir]
+	if !ok {
+		path, _ = importPath(dir) // Files outside of a module are matched by their package name only
+		ff.importPaths[dir] = path
+	}
+	if len(path) > 0 && strings.HasSuffix(pkgName, "_test") {
+		path += "_test"
+	}
+	return path
+}
+
+// functionNameCandidates returns the name of the function with and without the package name e.g. T.String and main.T.String,
+// as well as the import path of the package with and without the name of the function if known,
+// e.g. github.com/foo/bar and github.com/foo/bar.T.String.
+func functionNameCandidates(importPath, pkgName string, f *function) []string {
+	name := f.displayName()
+	candidates := []string{name, pkgName + "." + name}
+	if len(importPath) > 0 {
+		candidates = append(candidates, importPath, importPath+"."+name)
+	}
+	return candidates
+}
+
+// pathCandidates returns the path of the file and all of its suffixes starting at a directory boundary,
+// so that relative patterns like internal/generated/** match absolute paths as well.
+func pathCandidates(fileName string) []string {
+	if len(fileName) == 0 {
+		return nil
+	}
+	path := filepath.ToSlash(fileName)
+	candidates := []string{path}
+	for i := strings.Index(path, "/"); i != -1; i = strings.Index(path, "/") {
+		path = path[i+1:]
+		if len(path) > 0 {
+			candidates = append(candidates, path)
+		}
+	}
+	return candidates
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var result []*regexp.Regexp
+	for _, pattern := range patterns {
+		expr := globToRegex(pattern)
+		if strings.HasPrefix(pattern, regexPatternPrefix) {
+			expr = strings.TrimPrefix(pattern, regexPatternPrefix)
+		}
+		compiled, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %v", pattern, err)
+		}
+		result = append(result, compiled)
+	}
+	return result, nil
+}
+
+// globToRegex converts a glob to an anchored regular expression. "**" matches any sequence of characters,
+// "*" matches any sequence of characters except "/" and "?" matches any single character except "/".
+func globToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"testing"
+)
+
+func TestFunctionFilter(t *testing.T) {
+	method := &function{name: "String", receiverType: "T"}
+	funcLit := &function{name: "String.func1", receiverType: "T"}
+	fn := &function{name: "foo"}
+
+	tests := []struct {
+		Name     string
+		Options  Options
+		FileName string
+		Function *function
+		Accepted bool
+	}{
+		{Name: "NoPatterns", FileName: "main.go", Function: fn, Accepted: true},
+		{Name: "ExcludeMethodByGlob", Options: Options{Exclude: []string{"*.String"}}, FileName: "main.go", Function: method, Accepted: false},
+		{Name: "GlobDoesNotMatchFunctionLiteral", Options: Options{Exclude: []string{"*.String"}}, FileName: "main.go", Function: funcLit, Accepted: true},
+		{Name: "ExcludeFunctionLiteralByGlob", Options: Options{Exclude: []string{"*.String*"}}, FileName: "main.go", Function: funcLit, Accepted: false},
+		{Name: "ExcludeByPackageQualifiedName", Options: Options{Exclude: []string{"main.foo"}}, FileName: "main.go", Function: fn, Accepted: false},
+		{Name: "ExcludeByRegex", Options: Options{Exclude: []string{"re:^T\\."}}, FileName: "main.go", Function: method, Accepted: false},
+		{Name: "ExcludeByRelativePath", Options: Options{Exclude: []string{"internal/generated/**"}}, FileName: "/src/app/internal/generated/types/types.go", Function: fn, Accepted: false},
+		{Name: "SingleStarDoesNotMatchSlash", Options: Options{Exclude: []string{"internal/*.go"}}, FileName: "/src/app/internal/generated/types.go", Function: fn, Accepted: true},
+		{Name: "IncludeByImportPath", Options: Options{Include: []string{"github.com/DimitarPetrov/printracer/**"}}, FileName: "filter.go", Function: fn, Accepted: true},
+		{Name: "IncludeByImportPathNotMatching", Options: Options{Include: []string{"github.com/DimitarPetrov/printracer/internal/**"}}, FileName: "filter.go", Function: fn, Accepted: false},
+		{Name: "ExcludeByImportPathQualifiedName", Options: Options{Exclude: []string{"github.com/DimitarPetrov/printracer/tracing.T.*"}}, FileName: "filter.go", Function: method, Accepted: false},
+		{Name: "IncludeMatching", Options: Options{Include: []string{"foo", "T.*"}}, FileName: "main.go", Function: method, Accepted: true},
+		{Name: "IncludeNotMatching", Options: Options{Include: []string{"foo"}}, FileName: "main.go", Function: method, Accepted: false},
+		{Name: "ExcludeTakesPrecedence", Options: Options{Include: []string{"**"}, Exclude: []string{"main.go"}}, FileName: "/src/app/main.go", Function: fn, Accepted: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			filter, err := newFunctionFilter(test.Options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filter.accepts(test.FileName, "main", test.Function) != test.Accepted {
+				t.Errorf("Assertion failed! Expected accepted to be %v", test.Accepted)
+			}
+		})
+	}
+}
+
+func TestFunctionFilterWithInvalidPattern(t *testing.T) {
+	if _, err := newFunctionFilter(Options{Exclude: []string{"re:("}}); err == nil {
+		t.Error("Assertion failed! Expected error for invalid regular expression")
+	}
+}
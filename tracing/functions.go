@@ -0,0 +1,275 @@
+package tracing
+
+import (
+	"fmt"
+	"github.com/dave/dst"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strconv"
+)
+
+const generatedResultNamePrefix = "printracerResult"
+
+// function describes a function declaration or a function literal which is subject to instrumentation.
+type function struct {
+	// name is the name of the function within its package the way the go runtime reports it, e.g. main.func1.
+	name string
+	// position is the source position of a function literal (e.g. main.go:12). Empty for function declarations.
+	position string
+	// receiverType is the name of the receiver type of a method or of the method enclosing a function literal, e.g. T.
+	receiverType string
+	// testingImport is the name the testing package is imported with in the file of the function. Empty if not imported.
+	testingImport string
+	// rtImport is the name the rt package is imported with in the file of the function.
+	rtImport string
+	// generatedResults are the names given to unnamed and blank results of the function during instrumentation.
+	generatedResults map[string]bool
+	// typedArgs maps the parameters printed according to their types to the functions of the rt package
+	// they are passed to instead of Arg, e.g. s to String.
+	typedArgs map[string]string
+	// redacted are the parameters printed as <redacted> instead of by value.
+	redacted map[string]bool
+	// configVar is the name of the package-level variable holding the runtime configuration of the package,
+	// which is passed to Enter. Empty if the package is instrumented with the default options.
+	configVar string
+	node      dst.Node
+	recv      *dst.FieldList
+	typ       *dst.FuncType
+	body      *dst.BlockStmt
+}
+
+// displayName returns the name of the function prefixed with its receiver type if any, e.g. foo or T.String.
+func (f *function) displayName() string {
+	if len(f.receiverType) > 0 {
+		return f.receiverType + "." + f.name
+	}
+	return f.name
+}
+
+// argFunc returns the function of the rt package the parameter is passed to, e.g. Arg or String.
+func (f *function) argFunc(name string) string {
+	if argFunc, ok := f.typedArgs[name]; ok {
+		return argFunc
+	}
+	return "Arg"
+}
+
+func (f *function) isLiteral() bool {
+	_, ok := f.node.(*dst.FuncLit)
+	return ok
+}
+
+// collectFunctions returns all function declarations and function literals with bodies in the file.
+// Function literals are named the way the go runtime names them: after the enclosing function
+// followed by ".funcN" (e.g. foo.func1) and ".N" for nested literals (e.g. foo.func1.1).
+// testingImport is the name the testing package is imported with in the file, see importName.
+func collectFunctions(file *dst.File, testingImport string) []*function {
+	var functions []*function
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *dst.FuncDecl:
+			if d.Body == nil { // Functions implemented outside of go (e.g. in assembly)
+				continue
+			}
+			receiverType := receiverTypeName(d.Recv)
+			redacted := redactDirectiveNames(d.Decs.Start)
+			functions = append(functions, &function{name: d.Name.Name, receiverType: receiverType, redacted: redacted, node: d, recv: d.Recv, typ: d.Type, body: d.Body})
+			for _, lit := range collectFuncLits(d.Body, d.Name.Name+".func%d") {
+				lit.receiverType = receiverType
+				lit.redacted = redactDirectiveNames(d.Decs.Start)
+				functions = append(functions, lit)
+			}
+		case *dst.GenDecl:
+			for _, lit := range collectFuncLits(d, "init.func%d") {
+				lit.redacted = redactDirectiveNames(d.Decs.Start)
+				functions = append(functions, lit)
+			}
+		}
+	}
+
+	for _, fn := range functions {
+		fn.testingImport = testingImport
+	}
+	return functions
+}
+
+// isTestingType reports whether the type is *testing.T, *testing.B, *testing.F or testing.TB.
+func isTestingType(typ dst.Expr, testingImport string) bool {
+	if len(testingImport) == 0 {
+		return false
+	}
+	allowed := map[string]bool{"TB": true}
+	if star, ok := typ.(*dst.StarExpr); ok {
+		typ = star.X
+		allowed = map[string]bool{"T": true, "B": true, "F": true}
+	}
+	sel, ok := typ.(*dst.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*dst.Ident)
+	return ok && pkg.Name == testingImport && allowed[sel.Sel.Name]
+}
+
+// receiverTypeName returns the name of the receiver type without the pointer, e.g. T for (t *T).
+func receiverTypeName(recv *dst.FieldList) string {
+	if recv == nil || len(recv.List) == 0 {
+		return ""
+	}
+	typ := recv.List[0].Type
+	if star, ok := typ.(*dst.StarExpr); ok {
+		typ = star.X
+	}
+	if ident, ok := typ.(*dst.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+func collectFuncLits(root dst.Node, nameFormat string) []*function {
+	var functions []*function
+	count := 0
+	dst.Inspect(root, func(n dst.Node) bool {
+		lit, ok := n.(*dst.FuncLit)
+		if !ok {
+			return true
+		}
+		count++
+		name := fmt.Sprintf(nameFormat, count)
+		functions = append(functions, &function{name: name, node: lit, typ: lit.Type, body: lit.Body})
+		functions = append(functions, collectFuncLits(lit.Body, name+".%d")...)
+		return false
+	})
+	return functions
+}
+
+// funcLitPosition returns the source position of a function literal in the form file.go:line.
+func funcLitPosition(fset *token.FileSet, lit ast.Node) string {
+	position := fset.Position(lit.Pos())
+	if len(position.Filename) == 0 {
+		return strconv.Itoa(position.Line)
+	}
+	return fmt.Sprintf("%s:%d", filepath.Base(position.Filename), position.Line)
+}
+
+// instrumentedFuncLitPosition extracts the source position of a function literal recorded during instrumentation,
+// i.e. the argument of prt.At(...) in the instrumentation statement.
+// Source position of the literal in an already instrumented file is not reliable as instrumentation adds lines.
+func instrumentedFuncLitPosition(stmt dst.Stmt) string {
+	deferStmt, ok := stmt.(*dst.DeferStmt)
+	if !ok {
+		return ""
+	}
+	enterCall, ok := deferStmt.Call.Fun.(*dst.CallExpr)
+	if !ok || len(enterCall.Args) == 0 {
+		return ""
+	}
+	args := enterCall.Args
+	if _, ok := args[0].(*dst.Ident); ok && len(args) > 1 { // Skip the runtime configuration
+		args = args[1:]
+	}
+	atCall, ok := args[0].(*dst.CallExpr)
+	if !ok || len(atCall.Args) != 1 {
+		return ""
+	}
+	if sel, ok := atCall.Fun.(*dst.SelectorExpr); !ok || sel.Sel.Name != "At" {
+		return ""
+	}
+	position, ok := atCall.Args[0].(*dst.BasicLit)
+	if !ok || position.Kind != token.STRING {
+		return ""
+	}
+	unquoted, err := strconv.Unquote(position.Value)
+	if err != nil {
+		return ""
+	}
+	return unquoted
+}
+
+// nameResults gives names to unnamed and blank results of the function, so that they can be printed on exit.
+// Generated names are like printracerResult0 and do not collide with the names used within the function (usedNames).
+func nameResults(f *function, usedNames map[string]bool) {
+	f.generatedResults = make(map[string]bool)
+	if f.typ.Results == nil {
+		return
+	}
+	i := 0
+	// Results are named after their position, unless the name is already used.
+	nextName := func() string {
+		name := generatedResultName(i)
+		for j := i + 1; usedNames[name] || f.generatedResults[name]; j++ {
+			name = generatedResultName(j)
+		}
+		f.generatedResults[name] = true
+		return name
+	}
+	for _, result := range f.typ.Results.List {
+		if len(result.Names) == 0 {
+			result.Names = []*dst.Ident{dst.NewIdent(nextName())}
+			i++
+			continue
+		}
+		for _, name := range result.Names {
+			if name.Name == "_" {
+				name.Name = nextName()
+			}
+			i++
+		}
+	}
+}
+
+// unnameResults reverts the result names given by nameResults.
+// Results are left unnamed if all of them were named during instrumentation, otherwise generated names are replaced by blanks.
+func unnameResults(f *function) {
+	if f.typ.Results == nil {
+		return
+	}
+	allGenerated := true
+	for _, result := range f.typ.Results.List {
+		if len(result.Names) != 1 || !f.generatedResults[result.Names[0].Name] {
+			allGenerated = false
+			break
+		}
+	}
+	for _, result := range f.typ.Results.List {
+		if allGenerated {
+			result.Names = nil
+			continue
+		}
+		for _, name := range result.Names {
+			if f.generatedResults[name.Name] {
+				name.Name = "_"
+			}
+		}
+	}
+}
+
+// instrumentedGeneratedResults extracts the names given to results during instrumentation,
+// i.e. the results printed without a name by prt.Result("", &name) in the instrumentation statement.
+func instrumentedGeneratedResults(stmt dst.Stmt) map[string]bool {
+	generated := make(map[string]bool)
+	deferStmt, ok := stmt.(*dst.DeferStmt)
+	if !ok {
+		return generated
+	}
+	for _, arg := range deferStmt.Call.Args {
+		resultCall, ok := arg.(*dst.CallExpr)
+		if !ok || len(resultCall.Args) != 2 {
+			continue
+		}
+		if name, ok := resultCall.Args[0].(*dst.BasicLit); !ok || name.Value != `""` {
+			continue
+		}
+		if ref, ok := resultCall.Args[1].(*dst.UnaryExpr); ok && ref.Op == token.AND {
+			if ident, ok := ref.X.(*dst.Ident); ok {
+				generated[ident.Name] = true
+			}
+		}
+	}
+	return generated
+}
+
+func generatedResultName(i int) string {
+	return generatedResultNamePrefix + strconv.Itoa(i)
+}
@@ -1,122 +1,231 @@
 package tracing
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/dave/dst"
 	"github.com/dave/dst/decorator"
 	"go/ast"
-	"go/parser"
 	"go/token"
 	"golang.org/x/tools/go/ast/astutil"
 	"io"
-	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
-const funcNameVarName = "funcName"
-const funcPCVarName = "funcPC"
-
-const callerFuncNameVarName = "caller"
-const defaultCallerName = "unknown"
-const callerFuncPCVarName = "callerPC"
-
-const callIDVarName = "callID"
+const rtPackagePath = "github.com/DimitarPetrov/printracer/rt"
+const rtPackageAlias = "prt"
 
 const printracerCommentWatermark = "/* prinTracer */"
 
-const instrumentationStmtsCount = 9 // Acts like a contract of how many statements instrumentation adds and deinstrumentation removes.
+// InstrumentationImports returns the imports the instrumentation adds, which are removed on revert once unused.
+// Import paths are mapped to the names they were imported with by versions which did not mark the imports they add,
+// including the imports of the legacy instrumentation. Empty names stand for imports without a name.
+func InstrumentationImports() map[string]string {
+	imports := map[string]string{rtPackagePath: rtPackageAlias}
+	for path, name := range legacyInstrumentationImports {
+		imports[path] = name
+	}
+	return imports
+}
+
+const instrumentationStmtsCount = 1 // Acts like a contract of how many statements instrumentation adds and deinstrumentation removes.
 
-func buildInstrumentationStmts(f *dst.FuncDecl) [instrumentationStmtsCount]dst.Stmt {
+func buildInstrumentationStmts(f *function, opts Options) [instrumentationStmtsCount]dst.Stmt {
 	return [instrumentationStmtsCount]dst.Stmt{
-		newAssignStmt(funcNameVarName, f.Name.Name),
-		newAssignStmt(callerFuncNameVarName, defaultCallerName),
-		newGetFuncNameIfStatement("0", funcPCVarName, funcNameVarName),
-		newGetFuncNameIfStatement("1", callerFuncPCVarName, callerFuncNameVarName),
-		newMakeByteSliceStmt(),
-		newRandReadStmt(),
-		newParseUUIDFromByteSliceStmt(callIDVarName),
-		&dst.ExprStmt{
-			X: newPrintExprWithArgs(buildEnteringFunctionArgs(f)),
-		},
-		&dst.DeferStmt{
-			Call: newPrintExprWithArgs(buildExitFunctionArgs()),
-		},
+		newEnterDeferStmt(f, opts),
 	}
 }
 
 type codeInstrumenter struct {
+	store FileStore
+	// types loads the type information of the instrumented packages, sharing their dependencies (see Options.TypeAware).
+	types *typeLoader
 }
 
-func NewCodeInstrumenter() CodeInstrumenter {
-	return &codeInstrumenter{}
+func NewCodeInstrumenter(store FileStore) CodeInstrumenter {
+	return &codeInstrumenter{store: store, types: newTypeLoader(store)}
 }
 
-func (ci *codeInstrumenter) InstrumentDirectory(path string) error {
+func (ci *codeInstrumenter) InstrumentDirectory(path string, opts Options) error {
 	fset := token.NewFileSet()
-	filter := func(info os.FileInfo) bool {
-		return testsFilter(info) && generatedFilter(path, info)
+	filter := func(fileName string, src []byte) bool {
+		return (opts.IncludeTests || testsFilter(fileName)) && runtimeConfigFilter(src) && (opts.IncludeGenerated || generatedFilter(src))
 	}
-	pkgs, err := parser.ParseDir(fset, path, filter, parser.ParseComments)
+	pkgs, err := parseDir(fset, ci.store, path, filter)
 	if err != nil {
 		return fmt.Errorf("failed parsing go files in directory %s: %v", path, err)
 	}
 
 	for _, pkg := range pkgs {
-		if err := ci.InstrumentPackage(fset, pkg); err != nil {
+		if err := ci.InstrumentPackage(fset, pkg, opts); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (ci *codeInstrumenter) InstrumentPackage(fset *token.FileSet, pkg *ast.Package) error {
+func (ci *codeInstrumenter) InstrumentPackage(fset *token.FileSet, pkg *ast.Package, opts Options) error {
+	var dir string
+	for fileName := range pkg.Files {
+		dir = filepath.Dir(fileName)
+	}
+	if len(dir) == 0 {
+		return nil
+	}
+	types, err := ci.loadTypeInfo(dir, opts)
+	if err != nil {
+		return err
+	}
+
+	packageNames := packageScopeNames(pkg)
+	configVar := uniqueName(runtimeConfigVar, packageNames)
+	fileConfigVar := configVar
+	// External test packages (e.g. foo_test) are configured by the runtime configuration of the package under test,
+	// which they import. A configuration file of their own would be a non-test file of another package in the directory.
+	externalTests := strings.HasSuffix(pkg.Name, "_test")
+	if len(runtimeConfigFields(opts)) == 0 || externalTests {
+		fileConfigVar = ""
+	}
 	for fileName, file := range pkg.Files {
-		sourceFile, err := os.OpenFile(fileName, os.O_TRUNC|os.O_WRONLY, 0664)
-		if err != nil {
-			return fmt.Errorf("failed opening file %s: %v", fileName, err)
-		}
-		if err := ci.InstrumentFile(fset, file, sourceFile); err != nil {
+		var buff bytes.Buffer
+		if err := ci.instrumentFile(fset, file, &buff, opts, packageNames, types, fileConfigVar); err != nil {
 			return fmt.Errorf("failed instrumenting file %s: %v", fileName, err)
 		}
+		if err := ci.store.WriteFile(fileName, buff.Bytes()); err != nil {
+			return fmt.Errorf("failed writing file %s: %v", fileName, err)
+		}
 	}
-	return nil
+	if externalTests {
+		return nil
+	}
+	return writeRuntimeConfigFile(ci.store, dir, pkg.Name, uniqueName(rtPackageAlias, packageNames), configVar, opts)
+}
+
+func (ci *codeInstrumenter) InstrumentFile(fset *token.FileSet, file *ast.File, out io.Writer, opts Options) error {
+	types, err := ci.loadTypeInfo(filepath.Dir(fset.Position(file.Pos()).Filename), opts)
+	if err != nil {
+		return err
+	}
+	return ci.instrumentFile(fset, file, out, opts, nil, types, "")
+}
+
+// loadTypeInfo loads the type information of the package in the directory if enabled by the options.
+func (ci *codeInstrumenter) loadTypeInfo(dir string, opts Options) (typeInfo, error) {
+	if !opts.TypeAware {
+		return nil, nil
+	}
+	return ci.types.load(dir, opts.IncludeTests)
 }
 
-func (ci *codeInstrumenter) InstrumentFile(fset *token.FileSet, file *ast.File, out io.Writer) error {
-	astutil.AddImport(fset, file, "fmt")
-	astutil.AddNamedImport(fset, file, "rt", "runtime")
-	astutil.AddImport(fset, file, "crypto/rand")
+// instrumentFile instruments the file with names which do not collide with the identifiers in the file
+// and the names declared in the package block (packageNames). Arguments are printed according to their types if known.
+// Functions pass the package-level variable holding the runtime configuration (configVar) to Enter unless empty.
+func (ci *codeInstrumenter) instrumentFile(fset *token.FileSet, file *ast.File, out io.Writer, opts Options, packageNames map[string]bool, types typeInfo, configVar string) error {
+	// The rt package is referred by the name it is already imported with, if any.
+	// Otherwise it is imported with a name which is not used in the file, so that it is neither shadowed nor redeclared.
+	rtImport := importName(file, rtPackagePath)
+	addedImport := len(rtImport) == 0
+	if addedImport {
+		rtImport = uniqueName(rtPackageAlias, identifierNames(file), packageNames)
+		astutil.AddNamedImport(fset, file, rtImport, rtPackagePath)
+	}
 
 	// Needed because ast does not support floating comments and deletes them.
 	// In order to preserve all comments we just pre-parse it to dst which treats them as first class citizens.
-	f, err := decorator.DecorateFile(fset, file)
+	dec := decorator.NewDecorator(fset)
+	f, err := dec.DecorateFile(file)
 	if err != nil {
 		return fmt.Errorf("failed converting file from ast to dst: %v", err)
 	}
 
-	dst.Inspect(f, func(n dst.Node) bool {
-		switch t := n.(type) {
-		case *dst.FuncDecl:
-			if !ci.hasInstrumentationWatermark(t) {
-				instrumentationStmts := buildInstrumentationStmts(t)
-				t.Body.List = append(instrumentationStmts[:], t.Body.List...)
+	if addedImport {
+		markAddedImport(f, rtImport, rtPackagePath)
+	}
 
-				t.Body.List[0].Decorations().Before = dst.EmptyLine
-				t.Body.List[0].Decorations().Start.Append(printracerCommentWatermark)
-				t.Body.List[instrumentationStmtsCount-1].Decorations().After = dst.EmptyLine
-				t.Body.List[instrumentationStmtsCount-1].Decorations().End.Append(printracerCommentWatermark)
-			}
+	filter, err := newFunctionFilter(opts)
+	if err != nil {
+		return err
+	}
+	redactor, err := newRedactor(opts)
+	if err != nil {
+		return err
+	}
+	fileName := fset.Position(file.Pos()).Filename
+
+	for _, fn := range collectFunctions(f, importName(file, "testing")) {
+		if hasInstrumentationWatermark(fn.body) || !filter.accepts(fileName, f.Name.Name, fn) {
+			continue
 		}
-		return true
-	})
+		if fn.isLiteral() {
+			fn.position = funcLitPosition(fset, dec.Ast.Nodes[fn.node])
+		}
+		fn.rtImport = rtImport
+		fn.configVar = configVar
+		fn.typedArgs = types.typedArgs(fset, dec, fn)
+		nameResults(fn, identifierNames(dec.Ast.Nodes[fn.node]))
+		redactor.redact(dec, fn)
+		// The instrumentation of bodies on a single line is not followed by an empty line,
+		// so that their layout is restored on revert, see removeInstrumentationStmts.
+		body := dec.Ast.Nodes[fn.body].(*ast.BlockStmt)
+		singleLine := len(fn.body.List) > 0 && fset.Position(body.Lbrace).Line == fset.Position(body.Rbrace).Line
+		instrumentationStmts := buildInstrumentationStmts(fn, opts)
+		fn.body.List = append(instrumentationStmts[:], fn.body.List...)
+
+		fn.body.List[0].Decorations().Before = dst.EmptyLine
+		fn.body.List[0].Decorations().Start.Append(printracerCommentWatermark)
+		fn.body.List[instrumentationStmtsCount-1].Decorations().After = dst.EmptyLine
+		if singleLine {
+			fn.body.List[instrumentationStmtsCount-1].Decorations().After = dst.NewLine
+		}
+		fn.body.List[instrumentationStmtsCount-1].Decorations().End.Append(printracerCommentWatermark)
+	}
 	return decorator.Fprint(out, f)
 }
 
-func (ci *codeInstrumenter) hasInstrumentationWatermark(f *dst.FuncDecl) bool {
-	if len(f.Body.List) > 0 {
-		firstStmntDecorations := f.Body.List[0].Decorations().Start.All()
+func hasInstrumentationWatermark(body *dst.BlockStmt) bool {
+	if len(body.List) > 0 {
+		firstStmntDecorations := body.List[0].Decorations().Start.All()
 		if len(firstStmntDecorations) > 0 && firstStmntDecorations[0] == printracerCommentWatermark {
 			return true
 		}
 	}
 	return false
 }
+
+// markAddedImport records that the import was added by the instrumentation by a watermark comment, e.g.:
+// prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+// Only imports with the watermark are removed on revert, so that imports of the code are preserved.
+func markAddedImport(file *dst.File, name, path string) {
+	for i, decl := range file.Decls {
+		genDecl, ok := decl.(*dst.GenDecl)
+		if !ok || genDecl.Tok != token.IMPORT {
+			continue
+		}
+		for j, spec := range genDecl.Specs {
+			importSpec := spec.(*dst.ImportSpec)
+			if importSpec.Name == nil || importSpec.Name.Name != name || importSpec.Path.Value != strconv.Quote(path) {
+				continue
+			}
+			importSpec.Decs.End.Replace(printracerCommentWatermark)
+			importSpec.Decs.After = dst.NewLine
+
+			// An ungrouped declaration (e.g. import "fmt") the import is added to is turned into a group without a closing
+			// parenthesis, which the watermark would follow. The import is declared on its own instead, so that the
+			// declaration is left as it is and restored on revert.
+			if genDecl.Lparen && !genDecl.Rparen {
+				genDecl.Specs = append(genDecl.Specs[:j], genDecl.Specs[j+1:]...)
+				genDecl.Lparen = false
+				for _, spec := range genDecl.Specs {
+					spec.Decorations().Before, spec.Decorations().After = dst.None, dst.None
+				}
+				importSpec.Decs.Before, importSpec.Decs.After = dst.None, dst.None
+				importDecl := &dst.GenDecl{Tok: token.IMPORT, Specs: []dst.Spec{importSpec}}
+				importDecl.Decs.Before = dst.NewLine
+				file.Decls = append(file.Decls[:i+1], append([]dst.Decl{importDecl}, file.Decls[i+1:]...)...)
+			}
+			return
+		}
+	}
+}
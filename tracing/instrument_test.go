@@ -7,6 +7,7 @@ import (
 	"go/token"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -26,28 +27,12 @@ func main() {
 
 const resultCodeWithoutImports = `package a
 
-import (
-	"crypto/rand"
-	"fmt"
-	rt "runtime"
-)
+import prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
 
-func test(i int, b bool) int {
+func test(i int, b bool) (printracerResult0 int) {
 
 	/* prinTracer */
-	funcName := "test"
-	caller := "unknown"
-	if funcPC, _, _, ok := rt.Caller(0); ok {
-		funcName = rt.FuncForPC(funcPC).Name()
-	}
-	if callerPC, _, _, ok := rt.Caller(1); ok {
-		caller = rt.FuncForPC(callerPC).Name()
-	}
-	idBytes := make([]byte, 16)
-	_, _ = rand.Read(idBytes)
-	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
-	fmt.Printf("Entering function %s called by %s with args (%v) (%v); callID=%s\n", funcName, caller, i, b, callID)
-	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+	defer prt.Enter(prt.Arg("i", i), prt.Arg("b", b))(prt.Result("", &printracerResult0)) /* prinTracer */
 
 	if b {
 		return i
@@ -58,19 +43,7 @@ func test(i int, b bool) int {
 func main() {
 
 	/* prinTracer */
-	funcName := "main"
-	caller := "unknown"
-	if funcPC, _, _, ok := rt.Caller(0); ok {
-		funcName = rt.FuncForPC(funcPC).Name()
-	}
-	if callerPC, _, _, ok := rt.Caller(1); ok {
-		caller = rt.FuncForPC(callerPC).Name()
-	}
-	idBytes := make([]byte, 16)
-	_, _ = rand.Read(idBytes)
-	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
-	fmt.Printf("Entering function %s called by %s; callID=%s\n", funcName, caller, callID)
-	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+	defer prt.Enter()() /* prinTracer */
 
 	i := test(2, false)
 }
@@ -78,29 +51,12 @@ func main() {
 
 const editedResultCodeWithoutImports = `package a
 
-import (
-	"crypto/rand"
-	"fmt"
-	rt "runtime"
-)
+import prt "github.com/DimitarPetrov/printracer/rt"
 
-func test(i int, b bool) int {
+func test(i int, b bool) (printracerResult0 int) {
 
 	/* prinTracer */
-	funcName := "test2"
-	caller := "unknown2"
-	if funcPC, _, _, ok := rt.Caller(0); ok {
-		funcName = rt.FuncForPC(funcPC).Name()
-	}
-	if callerPC, _, _, ok := rt.Caller(1); ok {
-		caller = rt.FuncForPC(callerPC).Name()
-	}
-	fmt.Println("test")
-	idBytes := make([]byte, 16)
-	_, _ = rand.Read(idBytes)
-	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
-	fmt.Printf("Entering function %s called by %s with args (%v) (%v); callID=%s\n", funcName, caller, i, b, callID)
-	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+	defer prt.Enter(prt.Arg("i", i), prt.Arg("b", !b))(prt.Result("", &printracerResult0)) /* prinTracer */
 
 	if b {
 		return i
@@ -110,19 +66,7 @@ func test(i int, b bool) int {
 
 func main() {
 
-	funcName := "main"
-	caller := "unknown"
-	if funcPC, _, _, ok := rt.Caller(0); ok {
-		funcName = rt.FuncForPC(funcPC).Name()
-	}
-	if callerPC, _, _, ok := rt.Caller(1); ok {
-		caller = rt.FuncForPC(callerPC).Name()
-	}
-	idBytes := make([]byte, 16)
-	_, _ = rand.Read(idBytes)
-	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
-	fmt.Printf("Entering function %s called by %s; callID=%s\n", funcName, caller, callID)
-	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID)
+	defer prt.Enter()() /* prinTracer */
 
 	i := test(2, false)
 }
@@ -149,27 +93,14 @@ func main() {
 const resultCodeWithFmtImport = `package a
 
 import (
-	"crypto/rand"
 	"fmt"
-	rt "runtime"
+	prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
 )
 
-func test(i int, b bool) int {
+func test(i int, b bool) (printracerResult0 int) {
 
 	/* prinTracer */
-	funcName := "test"
-	caller := "unknown"
-	if funcPC, _, _, ok := rt.Caller(0); ok {
-		funcName = rt.FuncForPC(funcPC).Name()
-	}
-	if callerPC, _, _, ok := rt.Caller(1); ok {
-		caller = rt.FuncForPC(callerPC).Name()
-	}
-	idBytes := make([]byte, 16)
-	_, _ = rand.Read(idBytes)
-	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
-	fmt.Printf("Entering function %s called by %s with args (%v) (%v); callID=%s\n", funcName, caller, i, b, callID)
-	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+	defer prt.Enter(prt.Arg("i", i), prt.Arg("b", b))(prt.Result("", &printracerResult0)) /* prinTracer */
 
 	if b {
 		return i
@@ -180,25 +111,63 @@ func test(i int, b bool) int {
 func main() {
 
 	/* prinTracer */
-	funcName := "main"
-	caller := "unknown"
-	if funcPC, _, _, ok := rt.Caller(0); ok {
-		funcName = rt.FuncForPC(funcPC).Name()
-	}
-	if callerPC, _, _, ok := rt.Caller(1); ok {
-		caller = rt.FuncForPC(callerPC).Name()
-	}
-	idBytes := make([]byte, 16)
-	_, _ = rand.Read(idBytes)
-	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
-	fmt.Printf("Entering function %s called by %s; callID=%s\n", funcName, caller, callID)
-	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+	defer prt.Enter()() /* prinTracer */
 
 	i := test(2, false)
 	fmt.Println(i)
 }
 `
 
+const codeWithSingleImport = `package a
+
+import "fmt"
+
+func main() {
+	fmt.Println("a")
+}
+`
+
+const resultCodeWithSingleImport = `package a
+
+import "fmt"
+import prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+
+func main() {
+
+	/* prinTracer */
+	defer prt.Enter()() /* prinTracer */
+
+	fmt.Println("a")
+}
+`
+
+const codeWithSingleImportAndCollidingName = `package a
+
+import "fmt"
+
+var prt = 1
+
+func main() {
+	fmt.Println(prt)
+}
+`
+
+const resultCodeWithSingleImportAndCollidingName = `package a
+
+import "fmt"
+import prt1 "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+
+var prt = 1
+
+func main() {
+
+	/* prinTracer */
+	defer prt1.Enter()() /* prinTracer */
+
+	fmt.Println(prt)
+}
+`
+
 const codeWithMultipleImports = `package a
 
 import (
@@ -222,28 +191,15 @@ func main() {
 const resultCodeWithMultipleImports = `package a
 
 import (
-	"crypto/rand"
 	"fmt"
-	rt "runtime"
+	prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
 	"strconv"
 )
 
-func test(i int, b bool) int {
+func test(i int, b bool) (printracerResult0 int) {
 
 	/* prinTracer */
-	funcName := "test"
-	caller := "unknown"
-	if funcPC, _, _, ok := rt.Caller(0); ok {
-		funcName = rt.FuncForPC(funcPC).Name()
-	}
-	if callerPC, _, _, ok := rt.Caller(1); ok {
-		caller = rt.FuncForPC(callerPC).Name()
-	}
-	idBytes := make([]byte, 16)
-	_, _ = rand.Read(idBytes)
-	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
-	fmt.Printf("Entering function %s called by %s with args (%v) (%v); callID=%s\n", funcName, caller, i, b, callID)
-	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+	defer prt.Enter(prt.Arg("i", i), prt.Arg("b", b))(prt.Result("", &printracerResult0)) /* prinTracer */
 
 	if b {
 		return i
@@ -254,19 +210,7 @@ func test(i int, b bool) int {
 func main() {
 
 	/* prinTracer */
-	funcName := "main"
-	caller := "unknown"
-	if funcPC, _, _, ok := rt.Caller(0); ok {
-		funcName = rt.FuncForPC(funcPC).Name()
-	}
-	if callerPC, _, _, ok := rt.Caller(1); ok {
-		caller = rt.FuncForPC(callerPC).Name()
-	}
-	idBytes := make([]byte, 16)
-	_, _ = rand.Read(idBytes)
-	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
-	fmt.Printf("Entering function %s called by %s; callID=%s\n", funcName, caller, callID)
-	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+	defer prt.Enter()() /* prinTracer */
 
 	i := test(2, false)
 	fmt.Println(strconv.Itoa(i))
@@ -295,28 +239,14 @@ func main() {
 const resultCodeWithImportsWithoutFmt = `package a
 
 import (
-	"crypto/rand"
-	"fmt"
-	rt "runtime"
+	prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
 	"strconv"
 )
 
-func test(i int, b bool) int {
+func test(i int, b bool) (printracerResult0 int) {
 
 	/* prinTracer */
-	funcName := "test"
-	caller := "unknown"
-	if funcPC, _, _, ok := rt.Caller(0); ok {
-		funcName = rt.FuncForPC(funcPC).Name()
-	}
-	if callerPC, _, _, ok := rt.Caller(1); ok {
-		caller = rt.FuncForPC(callerPC).Name()
-	}
-	idBytes := make([]byte, 16)
-	_, _ = rand.Read(idBytes)
-	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
-	fmt.Printf("Entering function %s called by %s with args (%v) (%v); callID=%s\n", funcName, caller, i, b, callID)
-	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+	defer prt.Enter(prt.Arg("i", i), prt.Arg("b", b))(prt.Result("", &printracerResult0)) /* prinTracer */
 
 	if b {
 		return i
@@ -327,19 +257,7 @@ func test(i int, b bool) int {
 func main() {
 
 	/* prinTracer */
-	funcName := "main"
-	caller := "unknown"
-	if funcPC, _, _, ok := rt.Caller(0); ok {
-		funcName = rt.FuncForPC(funcPC).Name()
-	}
-	if callerPC, _, _, ok := rt.Caller(1); ok {
-		caller = rt.FuncForPC(callerPC).Name()
-	}
-	idBytes := make([]byte, 16)
-	_, _ = rand.Read(idBytes)
-	callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
-	fmt.Printf("Entering function %s called by %s; callID=%s\n", funcName, caller, callID)
-	defer fmt.Printf("Exiting function %s called by %s; callID=%s\n", funcName, caller, callID) /* prinTracer */
+	defer prt.Enter()() /* prinTracer */
 
 	i := test(2, false)
 	s := strconv.Itoa(i)
@@ -348,11 +266,7 @@ func main() {
 
 const codeWithWatermarks = `package a
 
-import (
-	"crypto/rand"
-	"fmt"
-	rt "runtime"
-)
+import prt "github.com/DimitarPetrov/printracer/rt"
 
 func test(i int, b bool) int {
 	/* prinTracer */
@@ -377,94 +291,1155 @@ type test struct {
 
 const resultCodeWithoutFunction = `package a
 
-import (
-	"crypto/rand"
-	"fmt"
-	rt "runtime"
-)
+import prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
 
 type test struct {
 	a int
 }
 `
 
-func TestInstrumentFile(t *testing.T) {
-	tests := []struct {
-		Name       string
-		InputCode  string
-		OutputCode string
-	}{
-		{Name: "InstrumentFileWithoutImports", InputCode: codeWithoutImports, OutputCode: resultCodeWithoutImports},
-		{Name: "InstrumentFileWithFmtImportOnly", InputCode: codeWithFmtImport, OutputCode: resultCodeWithFmtImport},
-		{Name: "InstrumentFileWithMultipleImports", InputCode: codeWithMultipleImports, OutputCode: resultCodeWithMultipleImports},
-		{Name: "InstrumentFileWithoutFmtImport", InputCode: codeWithImportsWithoutFmt, OutputCode: resultCodeWithImportsWithoutFmt},
-		{Name: "InstrumentFileWithoutFunctions", InputCode: codeWithoutFunction, OutputCode: resultCodeWithoutFunction},
-		{Name: "InstrumentFileDoesNotAffectAlreadyInstrumentedFiles", InputCode: resultCodeWithFmtImport, OutputCode: resultCodeWithFmtImport},
-		{Name: "FunctionsWithWatermarksShouldNotBeInstrumented", InputCode: codeWithWatermarks, OutputCode: codeWithWatermarks},
-	}
+const codeWithFuncLits = `package a
 
-	for _, test := range tests {
-		t.Run(test.Name, func(t *testing.T) {
-			fset := token.NewFileSet()
-			file, err := parser.ParseFile(fset, "", test.InputCode, parser.ParseComments)
-			if err != nil {
-				t.Fatal(err)
-			}
-			var buff bytes.Buffer
-			if err := NewCodeInstrumenter().InstrumentFile(fset, file, &buff); err != nil {
-				t.Fatal(err)
-			}
+var global = func() int {
+	return 1
+}
 
-			if buff.String() != test.OutputCode {
-				t.Errorf("Assertion failed! Expected %s got %s", test.OutputCode, buff.String())
-			}
-		})
-	}
+func test(s []int) {
+	sort.Slice(s, func(i, j int) bool {
+		return s[i] < s[j]
+	})
+	go func() {
+		func() {
+			_ = global()
+		}()
+	}()
 }
+`
 
-func TestInstrumentDirectory(t *testing.T) {
-	if err := os.Mkdir("test", 0777); err != nil {
-		t.Fatal(err)
-	}
-	defer func() {
-		if err := os.RemoveAll("test"); err != nil {
-			t.Fatal(err)
-		}
+const resultCodeWithFuncLits = `package a
+
+import prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+
+var global = func() (printracerResult0 int) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.At("3"))(prt.Result("", &printracerResult0)) /* prinTracer */
+
+	return 1
+}
+
+func test(s []int) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Arg("s", s))() /* prinTracer */
+
+	sort.Slice(s, func(i, j int) (printracerResult0 bool) {
+
+		/* prinTracer */
+		defer prt.Enter(prt.At("8"), prt.Arg("i", i), prt.Arg("j", j))(prt.Result("", &printracerResult0)) /* prinTracer */
+
+		return s[i] < s[j]
+	})
+	go func() {
+
+		/* prinTracer */
+		defer prt.Enter(prt.At("11"))() /* prinTracer */
+
+		func() {
+
+			/* prinTracer */
+			defer prt.Enter(prt.At("12"))() /* prinTracer */
+
+			_ = global()
+		}()
+	}()
+}
+`
+
+const codeWithOneLineBodies = `package a
+
+import "sort"
+
+func sorted(xs []int) []int {
+	sort.Slice(xs, func(i, j int) bool { return xs[i] < xs[j] })
+	go func() { sort.Ints(xs); sort.Sort(sort.Reverse(sort.IntSlice(xs))) }()
+	return xs
+}
+
+func main() { sorted([]int{2, 1}) }
+`
+
+const resultCodeWithOneLineBodies = `package a
+
+import "sort"
+import prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+
+func sorted(xs []int) (printracerResult0 []int) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Arg("xs", xs))(prt.Result("", &printracerResult0)) /* prinTracer */
+
+	sort.Slice(xs, func(i, j int) (printracerResult0 bool) {
+
+		/* prinTracer */
+		defer prt.Enter(prt.At("6"), prt.Arg("i", i), prt.Arg("j", j))(prt.Result("", &printracerResult0)) /* prinTracer */
+		return xs[i] < xs[j]
+	})
+	go func() {
+
+		/* prinTracer */
+		defer prt.Enter(prt.At("7"))() /* prinTracer */
+		sort.Ints(xs)
+		sort.Sort(sort.Reverse(sort.IntSlice(xs)))
 	}()
+	return xs
+}
 
-	filePairs := []struct {
-		InputCode  string
-		OutputCode string
-	}{
-		{InputCode: codeWithoutImports, OutputCode: resultCodeWithoutImports},
-		{InputCode: codeWithFmtImport, OutputCode: resultCodeWithFmtImport},
-		{InputCode: codeWithMultipleImports, OutputCode: resultCodeWithMultipleImports},
-		{InputCode: codeWithImportsWithoutFmt, OutputCode: resultCodeWithImportsWithoutFmt},
-		{InputCode: codeWithoutFunction, OutputCode: resultCodeWithoutFunction},
-		{InputCode: resultCodeWithFmtImport, OutputCode: resultCodeWithFmtImport},
-	}
+func main() {
 
-	i := 0
-	for _, filePair := range filePairs {
-		if err := ioutil.WriteFile(fmt.Sprintf("test/test%d.go", i), []byte(filePair.InputCode), 0777); err != nil {
-			t.Fatal(err)
-		}
-		i++
+	/* prinTracer */
+	defer prt.Enter()() /* prinTracer */
+	sorted([]int{2, 1})
+}
+`
+
+const codeWithResults = `package a
+
+import (
+	"errors"
+)
+
+func div(a int, b int) (int, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
 	}
+	return a / b, nil
+}
 
-	if err := NewCodeInstrumenter().InstrumentDirectory("test"); err != nil {
-		t.Fatal(err)
+func named(a int) (res int, _ error) {
+	res = a * 2
+	return
+}
+`
+
+const resultCodeWithResults = `package a
+
+import (
+	"errors"
+	prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+)
+
+func div(a int, b int) (printracerResult0 int, printracerResult1 error) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Arg("a", a), prt.Arg("b", b))(prt.Result("", &printracerResult0), prt.Result("", &printracerResult1)) /* prinTracer */
+
+	if b == 0 {
+		return 0, errors.New("division by zero")
 	}
+	return a / b, nil
+}
 
-	i = 0
-	for _, filePair := range filePairs {
-		data, err := ioutil.ReadFile(fmt.Sprintf("test/test%d.go", i))
-		if err != nil {
-			t.Fatal(err)
-		}
-		if string(data) != filePair.OutputCode {
-			t.Error("Assertion failed!")
-		}
-		i++
+func named(a int) (res int, printracerResult1 error) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Arg("a", a))(prt.Result("res", &res), prt.Result("", &printracerResult1)) /* prinTracer */
+
+	res = a * 2
+	return
+}
+`
+
+const codeWithDifferentKindsOfParams = `package a
+
+func grouped(a, b int, c string) {
+	return
+}
+
+func unnamed(int, string) {
+	return
+}
+
+func blank(_ int, b bool) {
+	return
+}
+
+func variadic(format string, args ...interface{}) {
+	return
+}
+`
+
+const resultCodeWithDifferentKindsOfParams = `package a
+
+import prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+
+func grouped(a, b int, c string) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Arg("a", a), prt.Arg("b", b), prt.Arg("c", c))() /* prinTracer */
+
+	return
+}
+
+func unnamed(int, string) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Arg("", nil), prt.Arg("", nil))() /* prinTracer */
+
+	return
+}
+
+func blank(_ int, b bool) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Arg("", nil), prt.Arg("b", b))() /* prinTracer */
+
+	return
+}
+
+func variadic(format string, args ...interface{}) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Arg("format", format), prt.Arg("args...", args))() /* prinTracer */
+
+	return
+}
+`
+
+const codeWithMethods = `package a
+
+type T struct {
+	a int
+}
+
+func (t *T) pointer(i int) {
+	t.a = i
+}
+
+func (t T) value() {
+	return
+}
+
+func (T) unnamed() {
+	return
+}
+`
+
+const resultCodeWithMethods = `package a
+
+import prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+
+type T struct {
+	a int
+}
+
+func (t *T) pointer(i int) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Receiver("t", t), prt.Arg("i", i))() /* prinTracer */
+
+	t.a = i
+}
+
+func (t T) value() {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Receiver("t", t))() /* prinTracer */
+
+	return
+}
+
+func (T) unnamed() {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Receiver("", nil))() /* prinTracer */
+
+	return
+}
+`
+
+const resultCodeWithExcludedMethods = `package a
+
+import prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+
+type T struct {
+	a int
+}
+
+func (t *T) pointer(i int) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Receiver("t", t), prt.Arg("i", i))() /* prinTracer */
+
+	t.a = i
+}
+
+func (t T) value() {
+	return
+}
+
+func (T) unnamed() {
+	return
+}
+`
+
+const codeWithTests = `package a
+
+import (
+	"testing"
+)
+
+func TestFoo(t *testing.T) {
+	t.Run("bar", func(t *testing.T) {
+		helper(t, 1)
+	})
+}
+
+func BenchmarkFoo(b *testing.B) {
+	return
+}
+
+func FuzzFoo(f *testing.F) {
+	return
+}
+
+func helper(tb testing.TB, i int) {
+	tb.Helper()
+}
+`
+
+const resultCodeWithTests = `package a
+
+import (
+	prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+	"testing"
+)
+
+func TestFoo(t *testing.T) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Test("t", t))() /* prinTracer */
+
+	t.Run("bar", func(t *testing.T) {
+
+		/* prinTracer */
+		defer prt.Enter(prt.At("8"), prt.Test("t", t))() /* prinTracer */
+
+		helper(t, 1)
+	})
+}
+
+func BenchmarkFoo(b *testing.B) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Test("b", b))() /* prinTracer */
+
+	return
+}
+
+func FuzzFoo(f *testing.F) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Test("f", f))() /* prinTracer */
+
+	return
+}
+
+func helper(tb testing.TB, i int) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Test("tb", tb), prt.Arg("i", i))() /* prinTracer */
+
+	tb.Helper()
+}
+`
+
+const codeWithCollidingNames = `package a
+
+import (
+	prt "fmt"
+)
+
+func print(prt1 string) (int, error) {
+	var printracerResult0 int
+	return prt.Println(prt1, printracerResult0)
+}
+
+func call(f func() (int, error)) (_ int, err error) {
+	printracerResult1 := 1
+	_, err = f()
+	return printracerResult1, err
+}
+`
+
+const resultCodeWithCollidingNames = `package a
+
+import (
+	prt "fmt"
+	prt2 "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+)
+
+func print(prt1 string) (printracerResult1 int, printracerResult2 error) {
+
+	/* prinTracer */
+	defer prt2.Enter(prt2.Arg("prt1", prt1))(prt2.Result("", &printracerResult1), prt2.Result("", &printracerResult2)) /* prinTracer */
+
+	var printracerResult0 int
+	return prt.Println(prt1, printracerResult0)
+}
+
+func call(f func() (int, error)) (printracerResult0 int, err error) {
+
+	/* prinTracer */
+	defer prt2.Enter(prt2.Arg("f", f))(prt2.Result("", &printracerResult0), prt2.Result("err", &err)) /* prinTracer */
+
+	printracerResult1 := 1
+	_, err = f()
+	return printracerResult1, err
+}
+`
+
+const codeWithRtImports = `package a
+
+import (
+	"github.com/DimitarPetrov/printracer/rt"
+)
+
+func init() {
+	rt.Configure(rt.Config{Format: "json"})
+}
+`
+
+const resultCodeWithRtImports = `package a
+
+import (
+	"github.com/DimitarPetrov/printracer/rt"
+)
+
+func init() {
+
+	/* prinTracer */
+	defer rt.Enter()() /* prinTracer */
+
+	rt.Configure(rt.Config{Format: "json"})
+}
+`
+
+const codeWithBlankRtImport = `package a
+
+import (
+	_ "github.com/DimitarPetrov/printracer/rt"
+)
+
+func test(i int) {
+	return
+}
+`
+
+const resultCodeWithBlankRtImport = `package a
+
+import (
+	_ "github.com/DimitarPetrov/printracer/rt"
+	prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+)
+
+func test(i int) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Arg("i", i))() /* prinTracer */
+
+	return
+}
+`
+
+const codeWithTypes = `package a
+
+import (
+	"context"
+	"errors"
+)
+
+type myErr struct{}
+
+func (*myErr) Error() string {
+	return ""
+}
+
+type name string
+
+func typed(s string, n name, err error, e *myErr, ctx context.Context, f func(int) error, c chan int, b []byte, i int, strs ...string) {
+	_ = errors.New(s)
+}
+`
+
+const resultCodeWithTypes = `package a
+
+import (
+	"context"
+	"errors"
+	prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+)
+
+type myErr struct{}
+
+func (*myErr) Error() (printracerResult0 string) {
+
+	/* prinTracer */
+	defer prt.Enter()(prt.Result("", &printracerResult0)) /* prinTracer */
+
+	return ""
+}
+
+type name string
+
+func typed(s string, n name, err error, e *myErr, ctx context.Context, f func(int) error, c chan int, b []byte, i int, strs ...string) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.String("s", s), prt.String("n", n), prt.Error("err", err), prt.Error("e", e), prt.Context("ctx", ctx), prt.Type("f", f), prt.Type("c", c), prt.Bytes("b", b), prt.Arg("i", i), prt.Arg("strs...", strs))() /* prinTracer */
+
+	_ = errors.New(s)
+}
+`
+
+const codeWithSensitiveParams = `package a
+
+type Credentials struct {
+	user string
+}
+
+// login logs the user in.
+//printracer:redact otp
+func login(user, password string, apiKey string, otp int, creds *Credentials, secret ...string) {
+	func(token string, otp int) {
+		return
+	}(secret[0], otp)
+}
+
+func (c *Credentials) Renew(newPassword string) (accessToken string, _ *Credentials, err error) {
+	return newPassword, c, nil
+}
+
+func current() *Credentials {
+	return nil
+}
+
+func lookup(cache map[string]string, key string, tokens []string, monkey int) string {
+	return cache[key]
+}
+`
+
+const resultCodeWithSensitiveParams = `package a
+
+import prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+
+type Credentials struct {
+	user string
+}
+
+// login logs the user in.
+//printracer:redact otp
+func login(user, password string, apiKey string, otp int, creds *Credentials, secret ...string) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Arg("user", user), prt.Redacted("password"), prt.Redacted("apiKey"), prt.Redacted("otp"), prt.Redacted("creds"), prt.Redacted("secret..."))() /* prinTracer */
+
+	func(token string, otp int) {
+
+		/* prinTracer */
+		defer prt.Enter(prt.At("10"), prt.Redacted("token"), prt.Redacted("otp"))() /* prinTracer */
+
+		return
+	}(secret[0], otp)
+}
+
+func (c *Credentials) Renew(newPassword string) (accessToken string, printracerResult1 *Credentials, err error) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.RedactedReceiver("c"), prt.Redacted("newPassword"))(prt.RedactedResult("accessToken", &accessToken), prt.RedactedResult("", &printracerResult1), prt.Result("err", &err)) /* prinTracer */
+
+	return newPassword, c, nil
+}
+
+func current() (printracerResult0 *Credentials) {
+
+	/* prinTracer */
+	defer prt.Enter()(prt.RedactedResult("", &printracerResult0)) /* prinTracer */
+
+	return nil
+}
+
+func lookup(cache map[string]string, key string, tokens []string, monkey int) (printracerResult0 string) {
+
+	/* prinTracer */
+	defer prt.Enter(prt.Arg("cache", cache), prt.Arg("key", key), prt.Arg("tokens", tokens), prt.Arg("monkey", monkey))(prt.Result("", &printracerResult0)) /* prinTracer */
+
+	return cache[key]
+}
+`
+
+func TestInstrumentFile(t *testing.T) {
+	tests := []struct {
+		Name       string
+		InputCode  string
+		OutputCode string
+		Options    Options
+	}{
+		{Name: "InstrumentFileWithoutImports", InputCode: codeWithoutImports, OutputCode: resultCodeWithoutImports},
+		{Name: "InstrumentFileWithFmtImportOnly", InputCode: codeWithFmtImport, OutputCode: resultCodeWithFmtImport},
+		{Name: "InstrumentFileWithSingleImport", InputCode: codeWithSingleImport, OutputCode: resultCodeWithSingleImport},
+		{Name: "InstrumentFileWithSingleImportAndCollidingName", InputCode: codeWithSingleImportAndCollidingName, OutputCode: resultCodeWithSingleImportAndCollidingName},
+		{Name: "InstrumentFileWithMultipleImports", InputCode: codeWithMultipleImports, OutputCode: resultCodeWithMultipleImports},
+		{Name: "InstrumentFileWithoutFmtImport", InputCode: codeWithImportsWithoutFmt, OutputCode: resultCodeWithImportsWithoutFmt},
+		{Name: "InstrumentFileWithoutFunctions", InputCode: codeWithoutFunction, OutputCode: resultCodeWithoutFunction},
+		{Name: "InstrumentFileWithFunctionLiterals", InputCode: codeWithFuncLits, OutputCode: resultCodeWithFuncLits},
+		{Name: "InstrumentFileWithOneLineBodies", InputCode: codeWithOneLineBodies, OutputCode: resultCodeWithOneLineBodies},
+		{Name: "InstrumentFileWithResults", InputCode: codeWithResults, OutputCode: resultCodeWithResults},
+		{Name: "InstrumentFileWithDifferentKindsOfParams", InputCode: codeWithDifferentKindsOfParams, OutputCode: resultCodeWithDifferentKindsOfParams},
+		{Name: "InstrumentFileWithReceivers", InputCode: codeWithMethods, OutputCode: resultCodeWithMethods, Options: Options{PrintReceivers: true}},
+		{Name: "InstrumentFileWithExcludedFunctions", InputCode: codeWithMethods, OutputCode: resultCodeWithExcludedMethods, Options: Options{PrintReceivers: true, Exclude: []string{"*.value", "re:unnamed$"}}},
+		{Name: "InstrumentFileWithTests", InputCode: codeWithTests, OutputCode: resultCodeWithTests},
+		{Name: "InstrumentFileWithSensitiveParams", InputCode: codeWithSensitiveParams, OutputCode: resultCodeWithSensitiveParams, Options: Options{PrintReceivers: true, Redact: append(DefaultRedactRules, "type:*Credentials")}},
+		{Name: "InstrumentFileWithCollidingNames", InputCode: codeWithCollidingNames, OutputCode: resultCodeWithCollidingNames},
+		{Name: "InstrumentFileReusingRtImport", InputCode: codeWithRtImports, OutputCode: resultCodeWithRtImports},
+		{Name: "InstrumentFileWithBlankRtImport", InputCode: codeWithBlankRtImport, OutputCode: resultCodeWithBlankRtImport},
+		{Name: "InstrumentFileDoesNotAffectAlreadyInstrumentedFiles", InputCode: resultCodeWithFmtImport, OutputCode: resultCodeWithFmtImport},
+		{Name: "FunctionsWithWatermarksShouldNotBeInstrumented", InputCode: codeWithWatermarks, OutputCode: codeWithWatermarks},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "", test.InputCode, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buff bytes.Buffer
+			if err := NewCodeInstrumenter(NewFileStore()).InstrumentFile(fset, file, &buff, test.Options); err != nil {
+				t.Fatal(err)
+			}
+
+			if buff.String() != test.OutputCode {
+				t.Errorf("Assertion failed! Expected %s got %s", test.OutputCode, buff.String())
+			}
+		})
+	}
+}
+
+func TestInstrumentDirectory(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	filePairs := []struct {
+		InputCode  string
+		OutputCode string
+	}{
+		{InputCode: codeWithoutImports, OutputCode: resultCodeWithoutImports},
+		{InputCode: codeWithFmtImport, OutputCode: resultCodeWithFmtImport},
+		{InputCode: codeWithMultipleImports, OutputCode: resultCodeWithMultipleImports},
+		{InputCode: codeWithImportsWithoutFmt, OutputCode: resultCodeWithImportsWithoutFmt},
+		{InputCode: codeWithoutFunction, OutputCode: resultCodeWithoutFunction},
+		{InputCode: resultCodeWithFmtImport, OutputCode: resultCodeWithFmtImport},
+	}
+
+	i := 0
+	for _, filePair := range filePairs {
+		if err := ioutil.WriteFile(fmt.Sprintf("test/test%d.go", i), []byte(filePair.InputCode), 0777); err != nil {
+			t.Fatal(err)
+		}
+		i++
+	}
+
+	store := NewFileStore()
+	if err := NewCodeInstrumenter(store).InstrumentDirectory("test", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	i = 0
+	for _, filePair := range filePairs {
+		data, err := ioutil.ReadFile(fmt.Sprintf("test/test%d.go", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != filePair.OutputCode {
+			t.Error("Assertion failed!")
+		}
+		i++
+	}
+}
+
+const runtimeConfigWithJSONFormat = `// Code generated by printracer. DO NOT EDIT.
+
+package a
+
+import prt "github.com/DimitarPetrov/printracer/rt"
+
+// printracerConfig is passed to every instrumented function of the package, so that the configuration
+// is applied before any of them is called.
+var printracerConfig = prt.Configure(prt.Config{
+	Format: "json",
+})
+`
+
+const runtimeConfigWithJSONFormatAndFileOutput = `// Code generated by printracer. DO NOT EDIT.
+
+package a
+
+import prt "github.com/DimitarPetrov/printracer/rt"
+
+// printracerConfig is passed to every instrumented function of the package, so that the configuration
+// is applied before any of them is called.
+var printracerConfig = prt.Configure(prt.Config{
+	Format: "json",
+	Output: "/tmp/trace.txt",
+})
+`
+
+const runtimeConfigWithMaxArgLen = `// Code generated by printracer. DO NOT EDIT.
+
+package a
+
+import prt "github.com/DimitarPetrov/printracer/rt"
+
+// printracerConfig is passed to every instrumented function of the package, so that the configuration
+// is applied before any of them is called.
+var printracerConfig = prt.Configure(prt.Config{
+	MaxArgLen: 1024,
+})
+`
+
+const runtimeConfigWithSampling = `// Code generated by printracer. DO NOT EDIT.
+
+package a
+
+import prt "github.com/DimitarPetrov/printracer/rt"
+
+// printracerConfig is passed to every instrumented function of the package, so that the configuration
+// is applied before any of them is called.
+var printracerConfig = prt.Configure(prt.Config{
+	SampleRate: 100,
+	RateLimit:  10,
+	MaxEvents:  100000,
+})
+`
+
+const runtimeConfigWithUUIDv7CallIDs = `// Code generated by printracer. DO NOT EDIT.
+
+package a
+
+import prt "github.com/DimitarPetrov/printracer/rt"
+
+// printracerConfig is passed to every instrumented function of the package, so that the configuration
+// is applied before any of them is called.
+var printracerConfig = prt.Configure(prt.Config{
+	CallIDs: "uuid7",
+})
+`
+
+const runtimeConfigWithDefaults = `// Code generated by printracer. DO NOT EDIT.
+
+package a
+
+import prt "github.com/DimitarPetrov/printracer/rt"
+
+// printracerConfig is passed to every instrumented function of the package, so that the configuration
+// is applied before any of them is called.
+var printracerConfig = prt.Configure(prt.Config{})
+`
+
+func TestInstrumentDirectoryGeneratesRuntimeConfig(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err := ioutil.WriteFile("test/test.go", []byte(codeWithoutImports), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Options Options
+		Config  string
+	}{
+		{Options: Options{Format: JSONFormat}, Config: runtimeConfigWithJSONFormat},
+		{Options: Options{Format: JSONFormat, Output: "/tmp/trace.txt"}, Config: runtimeConfigWithJSONFormatAndFileOutput},
+		{Options: Options{MaxArgLen: 1024}, Config: runtimeConfigWithMaxArgLen},
+		{Options: Options{SampleRate: 100, RateLimit: 10, MaxEvents: 100000}, Config: runtimeConfigWithSampling},
+		{Options: Options{CallIDs: UUIDv7CallIDs}, Config: runtimeConfigWithUUIDv7CallIDs},
+	}
+
+	store := NewFileStore()
+	instrumenter := NewCodeInstrumenter(store)
+	for _, test := range tests {
+		if err := ioutil.WriteFile("test/test.go", []byte(codeWithoutImports), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := instrumenter.InstrumentDirectory("test", test.Options); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := ioutil.ReadFile("test/" + runtimeConfigFileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.Config {
+			t.Errorf("Assertion failed! Expected %s got %s", test.Config, string(data))
+		}
+		if data, err = ioutil.ReadFile("test/test.go"); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(data, []byte("defer prt.Enter(printracerConfig)()")) {
+			t.Errorf("Assertion failed! Expected instrumented functions to refer to the runtime config got %s", string(data))
+		}
+	}
+
+	// Functions instrumented with the previous options still refer to the runtime config
+	if err := instrumenter.InstrumentDirectory("test", Options{Format: TextFormat, Output: StdoutOutput}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile("test/" + runtimeConfigFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != runtimeConfigWithDefaults {
+		t.Errorf("Assertion failed! Expected %s got %s", runtimeConfigWithDefaults, string(data))
+	}
+
+	if err := ioutil.WriteFile("test/test.go", []byte(codeWithoutImports), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := instrumenter.InstrumentDirectory("test", Options{Format: TextFormat, Output: StdoutOutput}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("test/" + runtimeConfigFileName); !os.IsNotExist(err) {
+		t.Error("Assertion failed! Expected runtime config to be removed when instrumenting with default options")
+	}
+}
+
+const codeWithPackageLevelInitializer = `package a
+
+var global = func() int {
+	return 42
+}()
+
+func foo() int {
+	return global
+}
+`
+
+const resultCodeWithPackageLevelInitializer = `package a
+
+import prt "github.com/DimitarPetrov/printracer/rt" /* prinTracer */
+
+var global = func() (printracerResult0 int) {
+
+	/* prinTracer */
+	defer prt.Enter(printracerConfig, prt.At("test.go:3"))(prt.Result("", &printracerResult0)) /* prinTracer */
+
+	return 42
+}()
+
+func foo() (printracerResult0 int) {
+
+	/* prinTracer */
+	defer prt.Enter(printracerConfig)(prt.Result("", &printracerResult0)) /* prinTracer */
+
+	return global
+}
+`
+
+func TestInstrumentDirectoryConfiguresPackageLevelInitializers(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err := ioutil.WriteFile("test/test.go", []byte(codeWithPackageLevelInitializer), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewFileStore()
+	if err := NewCodeInstrumenter(store).InstrumentDirectory("test", Options{Format: JSONFormat}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The initializer of global refers to printracerConfig, which is thus initialized first
+	expectedFiles := map[string]string{
+		"test/test.go":                  resultCodeWithPackageLevelInitializer,
+		"test/" + runtimeConfigFileName: runtimeConfigWithJSONFormat,
+	}
+	for name, expected := range expectedFiles {
+		data, err := store.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("Assertion failed! Expected %s got %s", expected, string(data))
+		}
+	}
+
+	if err := NewCodeDeinstrumenter(store).DeinstrumentDirectory("test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewImportsGroomer(store).RemoveUnusedImportFromDirectory("test", InstrumentationImports()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := store.ReadFile("test/test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != codeWithPackageLevelInitializer {
+		t.Errorf("Assertion failed! Expected %s got %s", codeWithPackageLevelInitializer, string(data))
+	}
+	if _, err := store.ReadFile("test/" + runtimeConfigFileName); !os.IsNotExist(err) {
+		t.Error("Assertion failed! Expected runtime config to be removed on revert")
+	}
+}
+
+func TestInstrumentDirectoryWithTests(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err := ioutil.WriteFile("test/test_test.go", []byte(codeWithoutImports), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Options    Options
+		OutputCode string
+	}{
+		{Options: Options{}, OutputCode: codeWithoutImports},
+		{Options: Options{IncludeTests: true}, OutputCode: resultCodeWithoutImports},
+	}
+
+	for _, test := range tests {
+		store := NewFileStore()
+		if err := NewCodeInstrumenter(store).InstrumentDirectory("test", test.Options); err != nil {
+			t.Fatal(err)
+		}
+		if err := NewImportsGroomer(store).RemoveUnusedImportFromDirectory("test", map[string]string{rtPackagePath: rtPackageAlias}); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := store.ReadFile("test/test_test.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.OutputCode {
+			t.Errorf("Assertion failed! Expected %s got %s", test.OutputCode, string(data))
+		}
+	}
+}
+
+func TestInstrumentDirectoryWithExternalTests(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	files := map[string]string{
+		"test.go":      codeWithoutImports,
+		"test_test.go": strings.Replace(codeWithoutImports, "package a", "package a_test", 1),
+	}
+	for name, code := range files {
+		if err := ioutil.WriteFile("test/"+name, []byte(code), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewFileStore()
+	if err := NewCodeInstrumenter(store).InstrumentDirectory("test", Options{IncludeTests: true, Format: JSONFormat}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := store.ReadFile("test/" + runtimeConfigFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != runtimeConfigWithJSONFormat {
+		t.Errorf("Assertion failed! Expected runtime config of the package under test %s got %s", runtimeConfigWithJSONFormat, string(data))
+	}
+	if data, err = store.ReadFile("test/test_test.go"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("defer prt.Enter()()")) {
+		t.Errorf("Assertion failed! Expected external tests instrumented without the runtime config got %s", string(data))
+	}
+}
+
+func TestInstrumentDirectoryWithGeneratedFiles(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	generatedCode := "// Copyright 2020 The Authors.\n\n// Code generated by protoc-gen-go. DO NOT EDIT.\n\n" + codeWithoutImports
+	files := map[string]string{
+		"generated_handlers.go": codeWithoutImports,
+		"service.pb.go":         generatedCode,
+	}
+	for name, code := range files {
+		if err := ioutil.WriteFile("test/"+name, []byte(code), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		Options              Options
+		ExpectedInstrumented map[string]bool
+	}{
+		{Options: Options{}, ExpectedInstrumented: map[string]bool{"generated_handlers.go": true, "service.pb.go": false}},
+		{Options: Options{IncludeGenerated: true}, ExpectedInstrumented: map[string]bool{"generated_handlers.go": true, "service.pb.go": true}},
+	}
+
+	for _, test := range tests {
+		store := NewFileStore()
+		if err := NewCodeInstrumenter(store).InstrumentDirectory("test", test.Options); err != nil {
+			t.Fatal(err)
+		}
+
+		for name, expected := range test.ExpectedInstrumented {
+			data, err := store.ReadFile("test/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if instrumented := bytes.Contains(data, []byte(printracerCommentWatermark)); instrumented != expected {
+				t.Errorf("Assertion failed! Expected %s instrumented to be %t with options %+v", name, expected, test.Options)
+			}
+		}
+	}
+}
+
+func TestInstrumentDirectoryReturnsErrorForUnreadableFile(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err := os.Symlink("missing.go", "test/test.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewCodeInstrumenter(NewFileStore()).InstrumentDirectory("test", Options{}); err == nil {
+		t.Error("Assertion failed! Expected error reading dangling symlink")
+	}
+}
+
+func TestInstrumentDirectoryAvoidsNamesDeclaredInPackage(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	files := map[string]string{
+		"test.go": codeWithoutImports,
+		"vars.go": "package a\n\nvar prt, prt1 = 1, 2\n",
+	}
+	for name, code := range files {
+		if err := ioutil.WriteFile("test/"+name, []byte(code), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewFileStore()
+	if err := NewCodeInstrumenter(store).InstrumentDirectory("test", Options{Format: JSONFormat}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedImports := map[string]string{
+		"test/test.go":                  `prt2 "github.com/DimitarPetrov/printracer/rt"`,
+		"test/" + runtimeConfigFileName: `import prt2 "github.com/DimitarPetrov/printracer/rt"`,
+	}
+	for name, expectedImport := range expectedImports {
+		data, err := store.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(data, []byte(expectedImport)) {
+			t.Errorf("Assertion failed! Expected %s to contain %s got %s", name, expectedImport, string(data))
+		}
+	}
+}
+
+func TestInstrumentDirectoryWithTypes(t *testing.T) {
+	if err := os.MkdirAll("test/b", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// The packages are instrumented by the same instrumenter, sharing the type checked dependencies
+	for _, fileName := range []string{"test/test.go", "test/b/test.go"} {
+		if err := ioutil.WriteFile(fileName, []byte(codeWithTypes), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewFileStore()
+	instrumenter := NewCodeInstrumenter(store)
+	for _, dir := range []string{"test", "test/b"} {
+		if err := instrumenter.InstrumentDirectory(dir, Options{TypeAware: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, fileName := range []string{"test/test.go", "test/b/test.go"} {
+		data, err := store.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != resultCodeWithTypes {
+			t.Errorf("Assertion failed! Expected %s got %s", resultCodeWithTypes, string(data))
+		}
 	}
 }
@@ -6,11 +6,95 @@ import (
 	"io"
 )
 
+const (
+	// StdoutOutput writes the trace to the standard output. It is the default output.
+	StdoutOutput = "stdout"
+	// StderrOutput writes the trace to the standard error.
+	StderrOutput = "stderr"
+)
+
+const (
+	// TextFormat prints the trace as lines of text. It is the default format.
+	TextFormat = "text"
+	// JSONFormat prints the trace as JSON objects, one per line.
+	JSONFormat = "json"
+)
+
+const (
+	// CounterCallIDs identifies the calls by a counter unique within the traced process. It is the default strategy.
+	CounterCallIDs = "counter"
+	// ProcessCallIDs identifies the calls by a counter prefixed with the ID of the traced process.
+	ProcessCallIDs = "process"
+	// UUIDv7CallIDs identifies the calls by time-ordered UUIDs.
+	UUIDv7CallIDs = "uuid7"
+)
+
+// Options controls what gets printed by the instrumentation.
+type Options struct {
+	// PrintReceivers enables printing of method receivers on function entry.
+	// Pointer receivers are printed by address and value receivers by value.
+	PrintReceivers bool
+	// Format is the format of the trace: TextFormat or JSONFormat. Empty stands for TextFormat.
+	// Formats other than the default one are configured in a file generated in every instrumented package.
+	Format string
+	// Output is where the instrumented code writes the trace: StdoutOutput, StderrOutput or a path of a file
+	// the trace is appended to. Empty stands for StdoutOutput. Overridden by PRINTRACER_OUTPUT environment variable at runtime.
+	Output string
+	// Include and Exclude are glob patterns (or regular expressions when prefixed with "re:") selecting the functions
+	// to be instrumented. They are matched against the function name (e.g. foo, T.String or main.T.String) and the path
+	// of its file (e.g. internal/generated/**). A function is instrumented if it matches any of the include patterns
+	// (if any) and none of the exclude patterns.
+	Include []string
+	Exclude []string
+	// IncludeTests enables instrumentation of _test.go files, e.g. of test helpers.
+	IncludeTests bool
+	// IncludeGenerated enables instrumentation of generated go files, i.e. files with a comment matching
+	// ^// Code generated .* DO NOT EDIT\.$ before the package clause.
+	IncludeGenerated bool
+	// TypeAware enables loading the instrumented packages with type information, so that arguments are printed
+	// according to their types, e.g. strings quoted and errors by their messages.
+	TypeAware bool
+	// Redact are the rules matching the parameters which are printed as <redacted> instead of by value.
+	// Rules are globs (or regular expressions when prefixed with re:) matched against the names of the parameters,
+	// or against their types as written in the source when prefixed with type:, e.g. type:*auth.Credentials.
+	Redact []string
+	// MaxArgLen is the maximum length in bytes of a printed argument, receiver or result. Longer values are truncated
+	// and marked with the number of omitted bytes. Zero means no limit. Overridden by PRINTRACER_MAX_ARG_LEN
+	// environment variable at runtime.
+	MaxArgLen int
+	// SampleRate, RateLimit and MaxEvents sample the traced calls at runtime: 1 in SampleRate calls of every function,
+	// at most RateLimit calls of every function per second and no new calls once MaxEvents events were written.
+	// The calls made within a call which is not traced are not traced either, so that sampled traces are complete trees.
+	// Zero means no sampling.
+	SampleRate int
+	RateLimit  int
+	MaxEvents  int
+	// CallIDs is the strategy of the call IDs: CounterCallIDs, ProcessCallIDs or UUIDv7CallIDs. Empty stands for CounterCallIDs.
+	CallIDs string
+}
+
+//go:generate counterfeiter . FileStore
+
+// FileStore is where the files processed by printracer are read from and written to.
+// Changes are kept in memory until they are committed to disk.
+type FileStore interface {
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, content []byte) error
+	RemoveFile(path string) error
+	// Changes returns the changes of the files made since the last commit, ordered by path.
+	Changes() ([]FileChange, error)
+	Commit() error
+	// WriteOverlay writes the changes into the directory instead of committing them, along with an overlay file
+	// for the -overlay flag of go build and go test, and discards them. Returns the path of the overlay file.
+	WriteOverlay(dir string) (string, error)
+	Discard()
+}
+
 //go:generate counterfeiter . CodeInstrumenter
 type CodeInstrumenter interface {
-	InstrumentFile(fset *token.FileSet, file *ast.File, out io.Writer) error
-	InstrumentPackage(fset *token.FileSet, pkg *ast.Package) error
-	InstrumentDirectory(path string) error
+	InstrumentFile(fset *token.FileSet, file *ast.File, out io.Writer, opts Options) error
+	InstrumentPackage(fset *token.FileSet, pkg *ast.Package, opts Options) error
+	InstrumentDirectory(path string, opts Options) error
 }
 
 //go:generate counterfeiter . CodeDeinstrumenter
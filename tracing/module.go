@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"fmt"
+	"golang.org/x/mod/modfile"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// FindModule returns the root directory and the path of the module containing dir.
+func FindModule(dir string) (string, string, error) {
+	for {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			modulePath := modfile.ModulePath(data)
+			if len(modulePath) == 0 {
+				return "", "", fmt.Errorf("missing module path in %s", filepath.Join(dir, "go.mod"))
+			}
+			return dir, modulePath, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("go.mod file not found in %s or any of its parent directories", dir)
+		}
+		dir = parent
+	}
+}
+
+// importPath returns the import path of the package in dir, resolved from the module containing it.
+func importPath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	root, modulePath, err := FindModule(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return modulePath, nil
+	}
+	return modulePath + "/" + filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"go/ast"
+	"path/filepath"
+	"strconv"
+)
+
+// identifierNames returns the names of all identifiers referred to or declared within the node.
+// It is a conservative approximation of the names in scope, which the instrumentation code must not collide with.
+func identifierNames(node ast.Node) map[string]bool {
+	names := make(map[string]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			names[ident.Name] = true
+		}
+		return true
+	})
+	return names
+}
+
+// packageScopeNames returns the names declared in the package block by any of the files of the package.
+// Names imported in a file must not collide with them.
+func packageScopeNames(pkg *ast.Package) map[string]bool {
+	names := make(map[string]bool)
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					names[d.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.ValueSpec:
+						for _, name := range s.Names {
+							names[name.Name] = true
+						}
+					case *ast.TypeSpec:
+						names[s.Name.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// uniqueName returns the name if it is not taken or the name followed by the smallest positive number which is not taken, e.g. prt1.
+func uniqueName(name string, taken ...map[string]bool) string {
+	candidate := name
+	for i := 1; isTaken(candidate, taken); i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	return candidate
+}
+
+func isTaken(name string, taken []map[string]bool) bool {
+	for _, names := range taken {
+		if names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// importName returns the name the package with the import path is imported with in the file, e.g. prt for the rt package.
+// Empty if the package is not imported or its declarations cannot be referred by a qualified identifier.
+func importName(file *ast.File, path string) string {
+	for _, spec := range file.Imports {
+		if importPath, err := strconv.Unquote(spec.Path.Value); err != nil || importPath != path {
+			continue
+		}
+		if spec.Name == nil {
+			return filepath.Base(path)
+		}
+		if spec.Name.Name != "_" && spec.Name.Name != "." {
+			return spec.Name.Name
+		}
+	}
+	return ""
+}
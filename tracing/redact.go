@@ -0,0 +1,140 @@
+package tracing
+
+import (
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// redactDirective in the doc comment of a function lists its parameters which are printed as <redacted>,
+// e.g. //printracer:redact password otp. It applies to the function literals within the function as well.
+const redactDirective = "//printracer:redact"
+
+// Redaction rules prefixed with typeRedactRulePrefix match the types of the parameters, all the others their names.
+const typeRedactRulePrefix = "type:"
+
+// DefaultRedactRules match the names commonly holding credentials: names ending with password, secret or token
+// case-insensitively (e.g. newPassword or accessToken) and names ending with Key (e.g. apiKey, but not key or monkey).
+var DefaultRedactRules = []string{"re:(?i)(password|secret|token)$", "*Key"}
+
+// redactor decides which receivers, parameters and results are printed as <redacted> instead of by value, based on
+// the redaction rules of the options. Rules are patterns (see compilePatterns) matched against their names (e.g. apiKey)
+// or, when prefixed with type:, against their types as written in the source (e.g. type:*auth.Credentials).
+type redactor struct {
+	names []*regexp.Regexp
+	types []*regexp.Regexp
+}
+
+func newRedactor(opts Options) (*redactor, error) {
+	var nameRules, typeRules []string
+	for _, rule := range opts.Redact {
+		if strings.HasPrefix(rule, typeRedactRulePrefix) {
+			typeRules = append(typeRules, strings.TrimPrefix(rule, typeRedactRulePrefix))
+		} else {
+			nameRules = append(nameRules, rule)
+		}
+	}
+	names, err := compilePatterns(nameRules)
+	if err != nil {
+		return nil, err
+	}
+	typs, err := compilePatterns(typeRules)
+	if err != nil {
+		return nil, err
+	}
+	return &redactor{names: names, types: typs}, nil
+}
+
+// redact marks the receiver, the parameters and the results of the function matching any of the rules as redacted.
+// Names given to unnamed results by the instrumentation are matched by the type rules only.
+func (r *redactor) redact(dec *decorator.Decorator, f *function) {
+	var fields []*dst.Field
+	if f.recv != nil {
+		fields = append(fields, f.recv.List...)
+	}
+	fields = append(fields, f.typ.Params.List...)
+	if f.typ.Results != nil {
+		fields = append(fields, f.typ.Results.List...)
+	}
+	for _, field := range fields {
+		typ := ""
+		if expr, ok := dec.Ast.Nodes[field.Type].(ast.Expr); ok {
+			typ = types.ExprString(expr)
+		}
+		for _, name := range field.Names {
+			if name.Name == "_" {
+				continue
+			}
+			if (!f.generatedResults[name.Name] && matchesAny(r.names, []string{name.Name})) || matchesAny(r.types, []string{typ}) {
+				f.redacted[name.Name] = true
+			}
+		}
+	}
+}
+
+// redactDirectiveNames returns the names listed by the redact directives in the decorations, e.g. the doc comment of a function.
+func redactDirectiveNames(decorations dst.Decorations) map[string]bool {
+	names := make(map[string]bool)
+	for _, comment := range decorations.All() {
+		if !strings.HasPrefix(comment, redactDirective+" ") {
+			continue
+		}
+		for _, name := range strings.FieldsFunc(strings.TrimPrefix(comment, redactDirective), isRedactDirectiveSeparator) {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func isRedactDirectiveSeparator(r rune) bool {
+	return r == ' ' || r == '\t' || r == ','
+}
+
+// instrumentedRedactedNames extracts the redacted receiver, parameters and results from the instrumentation statement,
+// i.e. the arguments of prt.Enter(...) like prt.Redacted("password") or prt.RedactedReceiver("c")
+// and the arguments of the function it returns like prt.RedactedResult("token", &token).
+func instrumentedRedactedNames(stmt dst.Stmt) map[string]bool {
+	redacted := make(map[string]bool)
+	deferStmt, ok := stmt.(*dst.DeferStmt)
+	if !ok {
+		return redacted
+	}
+	enterCall, ok := deferStmt.Call.Fun.(*dst.CallExpr)
+	if !ok {
+		return redacted
+	}
+	for _, arg := range enterCall.Args {
+		argCall, ok := arg.(*dst.CallExpr)
+		if !ok || len(argCall.Args) != 1 {
+			continue
+		}
+		if sel, ok := argCall.Fun.(*dst.SelectorExpr); !ok || (sel.Sel.Name != "Redacted" && sel.Sel.Name != "RedactedReceiver") {
+			continue
+		}
+		if name, ok := argCall.Args[0].(*dst.BasicLit); ok {
+			if unquoted, err := strconv.Unquote(name.Value); err == nil {
+				redacted[strings.TrimSuffix(unquoted, "...")] = true
+			}
+		}
+	}
+	for _, arg := range deferStmt.Call.Args {
+		resultCall, ok := arg.(*dst.CallExpr)
+		if !ok || len(resultCall.Args) != 2 {
+			continue
+		}
+		if sel, ok := resultCall.Fun.(*dst.SelectorExpr); !ok || sel.Sel.Name != "RedactedResult" {
+			continue
+		}
+		if ref, ok := resultCall.Args[1].(*dst.UnaryExpr); ok && ref.Op == token.AND {
+			if ident, ok := ref.X.(*dst.Ident); ok {
+				redacted[ident.Name] = true
+			}
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,177 @@
+package tracing
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dave/dst"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Name of the file generated in every instrumented package which configures the rt package.
+const runtimeConfigFileName = "printracer_config.go"
+
+// Name of the package-level variable holding the runtime configuration, unless declared by the package.
+const runtimeConfigVar = "printracerConfig"
+
+const runtimeConfigFileHeader = "// Code generated by printracer. DO NOT EDIT."
+
+// runtimeConfigFields returns the fields of the rt.Config literal corresponding to the options, e.g. Format: "json".
+// Options which are not set are left to the defaults of the rt package.
+func runtimeConfigFields(opts Options) []string {
+	var fields []string
+	if len(opts.Format) > 0 && opts.Format != TextFormat {
+		fields = append(fields, "Format: "+strconv.Quote(opts.Format))
+	}
+	if len(opts.Output) > 0 && opts.Output != StdoutOutput {
+		fields = append(fields, "Output: "+strconv.Quote(opts.Output))
+	}
+	if opts.MaxArgLen > 0 {
+		fields = append(fields, "MaxArgLen: "+strconv.Itoa(opts.MaxArgLen))
+	}
+	if len(opts.CallIDs) > 0 && opts.CallIDs != CounterCallIDs {
+		fields = append(fields, "CallIDs: "+strconv.Quote(opts.CallIDs))
+	}
+	if opts.SampleRate > 1 {
+		fields = append(fields, "SampleRate: "+strconv.Itoa(opts.SampleRate))
+	}
+	if opts.RateLimit > 0 {
+		fields = append(fields, "RateLimit: "+strconv.Itoa(opts.RateLimit))
+	}
+	if opts.MaxEvents > 0 {
+		fields = append(fields, "MaxEvents: "+strconv.Itoa(opts.MaxEvents))
+	}
+	return fields
+}
+
+// writeRuntimeConfigFile generates the runtime configuration file of the package in the directory. It declares
+// a package-level variable (configVar) applying the configuration, which every instrumented function passes to Enter.
+// Go initializes the variable before any package-level variable or init function calling instrumented functions,
+// so that all of their calls are traced with the configuration.
+// Previously generated configuration file is removed if all the options are left to their defaults,
+// unless functions instrumented with it are left in the directory.
+// The rt package is imported with the given name, which must not collide with the names declared in the package block.
+func writeRuntimeConfigFile(store FileStore, dir, pkgName, rtImport, configVar string, opts Options) error {
+	fields := runtimeConfigFields(opts)
+	if len(fields) == 0 {
+		if err := removeRuntimeConfigFile(store, dir); err != nil {
+			return err
+		}
+		existingVar, exists, err := readRuntimeConfigVar(store, dir)
+		if err != nil || !exists {
+			return err
+		}
+		configVar = existingVar
+	}
+
+	var buff bytes.Buffer
+	buff.WriteString(runtimeConfigFileHeader + "\n\n")
+	buff.WriteString("package " + pkgName + "\n\n")
+	buff.WriteString("import " + rtImport + " " + strconv.Quote(rtPackagePath) + "\n\n")
+	buff.WriteString("// " + configVar + " is passed to every instrumented function of the package, so that the configuration\n")
+	buff.WriteString("// is applied before any of them is called.\n")
+	buff.WriteString("var " + configVar + " = " + rtImport + ".Configure(" + rtImport + ".Config{")
+	if len(fields) > 0 {
+		buff.WriteString("\n" + strings.Join(fields, ",\n") + ",\n")
+	}
+	buff.WriteString("})\n")
+
+	src, err := format.Source(buff.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed formatting runtime configuration: %v", err)
+	}
+	fileName := filepath.Join(dir, runtimeConfigFileName)
+	if err := store.WriteFile(fileName, src); err != nil {
+		return fmt.Errorf("failed writing file %s: %v", fileName, err)
+	}
+	return nil
+}
+
+// removeRuntimeConfigFile removes the runtime configuration file generated in the directory if any,
+// unless functions instrumented with it are left in the directory, e.g. modified manually.
+func removeRuntimeConfigFile(store FileStore, dir string) error {
+	configVar, exists, err := readRuntimeConfigVar(store, dir)
+	if err != nil || !exists {
+		return err
+	}
+	if len(configVar) > 0 {
+		referenced, err := runtimeConfigReferenced(store, dir, configVar)
+		if err != nil || referenced {
+			return err
+		}
+	}
+	fileName := filepath.Join(dir, runtimeConfigFileName)
+	if err := store.RemoveFile(fileName); err != nil {
+		return fmt.Errorf("failed removing file %s: %v", fileName, err)
+	}
+	return nil
+}
+
+// readRuntimeConfigVar returns the name of the variable declared by the runtime configuration file generated
+// in the directory, if any. The name is empty for files generated by versions configuring the rt package on init.
+func readRuntimeConfigVar(store FileStore, dir string) (string, bool, error) {
+	fileName := filepath.Join(dir, runtimeConfigFileName)
+	content, err := store.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed reading file %s: %v", fileName, err)
+	}
+	if runtimeConfigFilter(content) { // Not generated by printracer
+		return "", false, nil
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), fileName, content, 0)
+	if err != nil {
+		return "", false, fmt.Errorf("failed parsing file %s: %v", fileName, err)
+	}
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.VAR {
+			return genDecl.Specs[0].(*ast.ValueSpec).Names[0].Name, true, nil
+		}
+	}
+	return "", true, nil
+}
+
+// runtimeConfigReferenced reports whether any of the go files in the directory refers to the variable
+// declared by the runtime configuration file, i.e. whether functions instrumented with it are left.
+func runtimeConfigReferenced(store FileStore, dir, configVar string) (bool, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parseDir(fset, store, dir, func(fileName string, src []byte) bool {
+		return runtimeConfigFilter(src)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed parsing go files in directory %s: %v", dir, err)
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			if identifierNames(file)[configVar] {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// instrumentedConfigVar returns the name of the variable holding the runtime configuration passed to Enter
+// by the instrumentation statement, if any.
+func instrumentedConfigVar(stmt dst.Stmt) string {
+	deferStmt, ok := stmt.(*dst.DeferStmt)
+	if !ok {
+		return ""
+	}
+	enterCall, ok := deferStmt.Call.Fun.(*dst.CallExpr)
+	if !ok || len(enterCall.Args) == 0 {
+		return ""
+	}
+	if ident, ok := enterCall.Args[0].(*dst.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
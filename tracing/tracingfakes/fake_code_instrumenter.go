@@ -11,10 +11,11 @@ import (
 )
 
 type FakeCodeInstrumenter struct {
-	InstrumentDirectoryStub        func(string) error
+	InstrumentDirectoryStub        func(string, tracing.Options) error
 	instrumentDirectoryMutex       sync.RWMutex
 	instrumentDirectoryArgsForCall []struct {
 		arg1 string
+		arg2 tracing.Options
 	}
 	instrumentDirectoryReturns struct {
 		result1 error
@@ -22,12 +23,13 @@ type FakeCodeInstrumenter struct {
 	instrumentDirectoryReturnsOnCall map[int]struct {
 		result1 error
 	}
-	InstrumentFileStub        func(*token.FileSet, *ast.File, io.Writer) error
+	InstrumentFileStub        func(*token.FileSet, *ast.File, io.Writer, tracing.Options) error
 	instrumentFileMutex       sync.RWMutex
 	instrumentFileArgsForCall []struct {
 		arg1 *token.FileSet
 		arg2 *ast.File
 		arg3 io.Writer
+		arg4 tracing.Options
 	}
 	instrumentFileReturns struct {
 		result1 error
@@ -35,11 +37,12 @@ type FakeCodeInstrumenter struct {
 	instrumentFileReturnsOnCall map[int]struct {
 		result1 error
 	}
-	InstrumentPackageStub        func(*token.FileSet, *ast.Package) error
+	InstrumentPackageStub        func(*token.FileSet, *ast.Package, tracing.Options) error
 	instrumentPackageMutex       sync.RWMutex
 	instrumentPackageArgsForCall []struct {
 		arg1 *token.FileSet
 		arg2 *ast.Package
+		arg3 tracing.Options
 	}
 	instrumentPackageReturns struct {
 		result1 error
@@ -51,21 +54,23 @@ type FakeCodeInstrumenter struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeCodeInstrumenter) InstrumentDirectory(arg1 string) error {
+func (fake *FakeCodeInstrumenter) InstrumentDirectory(arg1 string, arg2 tracing.Options) error {
 	fake.instrumentDirectoryMutex.Lock()
 	ret, specificReturn := fake.instrumentDirectoryReturnsOnCall[len(fake.instrumentDirectoryArgsForCall)]
 	fake.instrumentDirectoryArgsForCall = append(fake.instrumentDirectoryArgsForCall, struct {
 		arg1 string
-	}{arg1})
-	fake.recordInvocation("InstrumentDirectory", []interface{}{arg1})
+		arg2 tracing.Options
+	}{arg1, arg2})
+	stub := fake.InstrumentDirectoryStub
+	fakeReturns := fake.instrumentDirectoryReturns
+	fake.recordInvocation("InstrumentDirectory", []interface{}{arg1, arg2})
 	fake.instrumentDirectoryMutex.Unlock()
-	if fake.InstrumentDirectoryStub != nil {
-		return fake.InstrumentDirectoryStub(arg1)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.instrumentDirectoryReturns
 	return fakeReturns.result1
 }
 
@@ -75,17 +80,17 @@ func (fake *FakeCodeInstrumenter) InstrumentDirectoryCallCount() int {
 	return len(fake.instrumentDirectoryArgsForCall)
 }
 
-func (fake *FakeCodeInstrumenter) InstrumentDirectoryCalls(stub func(string) error) {
+func (fake *FakeCodeInstrumenter) InstrumentDirectoryCalls(stub func(string, tracing.Options) error) {
 	fake.instrumentDirectoryMutex.Lock()
 	defer fake.instrumentDirectoryMutex.Unlock()
 	fake.InstrumentDirectoryStub = stub
 }
 
-func (fake *FakeCodeInstrumenter) InstrumentDirectoryArgsForCall(i int) string {
+func (fake *FakeCodeInstrumenter) InstrumentDirectoryArgsForCall(i int) (string, tracing.Options) {
 	fake.instrumentDirectoryMutex.RLock()
 	defer fake.instrumentDirectoryMutex.RUnlock()
 	argsForCall := fake.instrumentDirectoryArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeCodeInstrumenter) InstrumentDirectoryReturns(result1 error) {
@@ -111,23 +116,25 @@ func (fake *FakeCodeInstrumenter) InstrumentDirectoryReturnsOnCall(i int, result
 	}{result1}
 }
 
-func (fake *FakeCodeInstrumenter) InstrumentFile(arg1 *token.FileSet, arg2 *ast.File, arg3 io.Writer) error {
+func (fake *FakeCodeInstrumenter) InstrumentFile(arg1 *token.FileSet, arg2 *ast.File, arg3 io.Writer, arg4 tracing.Options) error {
 	fake.instrumentFileMutex.Lock()
 	ret, specificReturn := fake.instrumentFileReturnsOnCall[len(fake.instrumentFileArgsForCall)]
 	fake.instrumentFileArgsForCall = append(fake.instrumentFileArgsForCall, struct {
 		arg1 *token.FileSet
 		arg2 *ast.File
 		arg3 io.Writer
-	}{arg1, arg2, arg3})
-	fake.recordInvocation("InstrumentFile", []interface{}{arg1, arg2, arg3})
+		arg4 tracing.Options
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.InstrumentFileStub
+	fakeReturns := fake.instrumentFileReturns
+	fake.recordInvocation("InstrumentFile", []interface{}{arg1, arg2, arg3, arg4})
 	fake.instrumentFileMutex.Unlock()
-	if fake.InstrumentFileStub != nil {
-		return fake.InstrumentFileStub(arg1, arg2, arg3)
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.instrumentFileReturns
 	return fakeReturns.result1
 }
 
@@ -137,17 +144,17 @@ func (fake *FakeCodeInstrumenter) InstrumentFileCallCount() int {
 	return len(fake.instrumentFileArgsForCall)
 }
 
-func (fake *FakeCodeInstrumenter) InstrumentFileCalls(stub func(*token.FileSet, *ast.File, io.Writer) error) {
+func (fake *FakeCodeInstrumenter) InstrumentFileCalls(stub func(*token.FileSet, *ast.File, io.Writer, tracing.Options) error) {
 	fake.instrumentFileMutex.Lock()
 	defer fake.instrumentFileMutex.Unlock()
 	fake.InstrumentFileStub = stub
 }
 
-func (fake *FakeCodeInstrumenter) InstrumentFileArgsForCall(i int) (*token.FileSet, *ast.File, io.Writer) {
+func (fake *FakeCodeInstrumenter) InstrumentFileArgsForCall(i int) (*token.FileSet, *ast.File, io.Writer, tracing.Options) {
 	fake.instrumentFileMutex.RLock()
 	defer fake.instrumentFileMutex.RUnlock()
 	argsForCall := fake.instrumentFileArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeCodeInstrumenter) InstrumentFileReturns(result1 error) {
@@ -173,22 +180,24 @@ func (fake *FakeCodeInstrumenter) InstrumentFileReturnsOnCall(i int, result1 err
 	}{result1}
 }
 
-func (fake *FakeCodeInstrumenter) InstrumentPackage(arg1 *token.FileSet, arg2 *ast.Package) error {
+func (fake *FakeCodeInstrumenter) InstrumentPackage(arg1 *token.FileSet, arg2 *ast.Package, arg3 tracing.Options) error {
 	fake.instrumentPackageMutex.Lock()
 	ret, specificReturn := fake.instrumentPackageReturnsOnCall[len(fake.instrumentPackageArgsForCall)]
 	fake.instrumentPackageArgsForCall = append(fake.instrumentPackageArgsForCall, struct {
 		arg1 *token.FileSet
 		arg2 *ast.Package
-	}{arg1, arg2})
-	fake.recordInvocation("InstrumentPackage", []interface{}{arg1, arg2})
+		arg3 tracing.Options
+	}{arg1, arg2, arg3})
+	stub := fake.InstrumentPackageStub
+	fakeReturns := fake.instrumentPackageReturns
+	fake.recordInvocation("InstrumentPackage", []interface{}{arg1, arg2, arg3})
 	fake.instrumentPackageMutex.Unlock()
-	if fake.InstrumentPackageStub != nil {
-		return fake.InstrumentPackageStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.instrumentPackageReturns
 	return fakeReturns.result1
 }
 
@@ -198,17 +207,17 @@ func (fake *FakeCodeInstrumenter) InstrumentPackageCallCount() int {
 	return len(fake.instrumentPackageArgsForCall)
 }
 
-func (fake *FakeCodeInstrumenter) InstrumentPackageCalls(stub func(*token.FileSet, *ast.Package) error) {
+func (fake *FakeCodeInstrumenter) InstrumentPackageCalls(stub func(*token.FileSet, *ast.Package, tracing.Options) error) {
 	fake.instrumentPackageMutex.Lock()
 	defer fake.instrumentPackageMutex.Unlock()
 	fake.InstrumentPackageStub = stub
 }
 
-func (fake *FakeCodeInstrumenter) InstrumentPackageArgsForCall(i int) (*token.FileSet, *ast.Package) {
+func (fake *FakeCodeInstrumenter) InstrumentPackageArgsForCall(i int) (*token.FileSet, *ast.Package, tracing.Options) {
 	fake.instrumentPackageMutex.RLock()
 	defer fake.instrumentPackageMutex.RUnlock()
 	argsForCall := fake.instrumentPackageArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeCodeInstrumenter) InstrumentPackageReturns(result1 error) {
@@ -0,0 +1,193 @@
+package tracing
+
+import (
+	"fmt"
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// Functions of the rt package arguments are passed to instead of Arg when instrumenting with type information.
+const (
+	stringArgFunc  = "String"
+	errorArgFunc   = "Error"
+	contextArgFunc = "Context"
+	typeArgFunc    = "Type"
+	bytesArgFunc   = "Bytes"
+)
+
+var typedArgFuncs = map[string]bool{stringArgFunc: true, errorArgFunc: true, contextArgFunc: true, typeArgFunc: true, bytesArgFunc: true}
+
+// typePosition is the position of an identifier in a file.
+type typePosition struct {
+	fileName string
+	offset   int
+}
+
+// typeInfo maps the positions of the identifiers declared in a package to their types.
+type typeInfo map[typePosition]types.Type
+
+// typeLoader loads the type information of packages. The dependencies of the packages are type checked from source
+// once per loader, so that packages loaded by the same loader (e.g. the ones instrumented by a run) share them.
+type typeLoader struct {
+	store    FileStore
+	fset     *token.FileSet
+	importer types.Importer
+}
+
+func newTypeLoader(store FileStore) *typeLoader {
+	fset := token.NewFileSet()
+	return &typeLoader{store: store, fset: fset, importer: importer.ForCompiler(fset, "source", nil)}
+}
+
+// load loads the package in the directory along with its type information.
+// Files are loaded with their content in the store, so that positions match the ones of the parsed files.
+// Type errors (e.g. of packages which do not compile) are tolerated, leaving types which cannot be resolved unknown.
+func (tl *typeLoader) load(dir string, includeTests bool) (typeInfo, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed resolving directory %s: %v", dir, err)
+	}
+	infos, err := ioutil.ReadDir(absDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading directory %s: %v", dir, err)
+	}
+	overlay := make(map[string][]byte)
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".go" {
+			continue
+		}
+		fileName := filepath.Join(absDir, info.Name())
+		if content, err := tl.store.ReadFile(fileName); err == nil {
+			overlay[fileName] = content
+		}
+	}
+
+	// Packages are type checked here instead of by go/packages (packages.NeedTypes), as the sizes of types it resolves
+	// are not supported by recent go versions.
+	fset := tl.fset
+	cfg := &packages.Config{
+		Fset:    fset,
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedSyntax,
+		Dir:     absDir,
+		Tests:   includeTests,
+		Overlay: overlay,
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed loading package in directory %s: %v", dir, err)
+	}
+
+	info := make(typeInfo)
+	for _, pkg := range pkgs {
+		typesInfo := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+		conf := types.Config{
+			Importer: tl.importer,
+			Sizes:    types.SizesFor("gc", build.Default.GOARCH),
+			Error:    func(error) {},
+		}
+		_, _ = conf.Check(pkg.PkgPath, fset, pkg.Syntax, typesInfo)
+		for ident, obj := range typesInfo.Defs {
+			if obj == nil {
+				continue
+			}
+			position := fset.Position(ident.Pos())
+			info[typePosition{fileName: position.Filename, offset: position.Offset}] = obj.Type()
+		}
+	}
+	return info, nil
+}
+
+// typeOf returns the type of the declared identifier. Nil if unknown.
+func (ti typeInfo) typeOf(fset *token.FileSet, ident ast.Node) types.Type {
+	position := fset.Position(ident.Pos())
+	fileName, err := filepath.Abs(position.Filename)
+	if err != nil {
+		return nil
+	}
+	return ti[typePosition{fileName: fileName, offset: position.Offset}]
+}
+
+// typedArgs returns the parameters of the function which are printed according to their types
+// mapped to the functions of the rt package they are passed to, e.g. s to String.
+func (ti typeInfo) typedArgs(fset *token.FileSet, dec *decorator.Decorator, f *function) map[string]string {
+	typedArgs := make(map[string]string)
+	if ti == nil {
+		return typedArgs
+	}
+	for _, param := range f.typ.Params.List {
+		for _, name := range param.Names {
+			ident, ok := dec.Ast.Nodes[name]
+			if !ok || name.Name == "_" {
+				continue
+			}
+			if argFunc := typedArgFunc(ti.typeOf(fset, ident)); len(argFunc) > 0 {
+				typedArgs[name.Name] = argFunc
+			}
+		}
+	}
+	return typedArgs
+}
+
+// typedArgFunc returns the function of the rt package an argument of the type is passed to.
+// Empty for unknown types and types printed with %v.
+func typedArgFunc(typ types.Type) string {
+	if typ == nil {
+		return ""
+	}
+	if named, ok := typ.(*types.Named); ok && named.Obj().Pkg() != nil &&
+		named.Obj().Pkg().Path() == "context" && named.Obj().Name() == "Context" {
+		return contextArgFunc
+	}
+	if types.Implements(typ, types.Universe.Lookup("error").Type().Underlying().(*types.Interface)) {
+		return errorArgFunc
+	}
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		if t.Info()&types.IsString != 0 {
+			return stringArgFunc
+		}
+	case *types.Signature, *types.Chan:
+		return typeArgFunc
+	case *types.Slice:
+		if types.Identical(t.Elem(), types.Typ[types.Byte]) {
+			return bytesArgFunc
+		}
+	}
+	return ""
+}
+
+// instrumentedTypedArgs extracts the parameters printed according to their types from the instrumentation statement,
+// i.e. the arguments of prt.Enter(...) passed to other functions of the rt package than Arg, e.g. prt.String("s", s).
+func instrumentedTypedArgs(stmt dst.Stmt) map[string]string {
+	typedArgs := make(map[string]string)
+	deferStmt, ok := stmt.(*dst.DeferStmt)
+	if !ok {
+		return typedArgs
+	}
+	enterCall, ok := deferStmt.Call.Fun.(*dst.CallExpr)
+	if !ok {
+		return typedArgs
+	}
+	for _, arg := range enterCall.Args {
+		argCall, ok := arg.(*dst.CallExpr)
+		if !ok || len(argCall.Args) != 2 {
+			continue
+		}
+		sel, ok := argCall.Fun.(*dst.SelectorExpr)
+		if !ok || !typedArgFuncs[sel.Sel.Name] {
+			continue
+		}
+		if name, ok := argCall.Args[1].(*dst.Ident); ok {
+			typedArgs[name.Name] = sel.Sel.Name
+		}
+	}
+	return typedArgs
+}
@@ -1,29 +1,32 @@
 package tracing
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/dave/dst/decorator"
 	"go/ast"
-	"go/parser"
 	"go/token"
 	"golang.org/x/tools/go/ast/astutil"
 	"io"
-	"os"
+	"path/filepath"
+	"strconv"
 )
 
 type importsGroomer struct {
+	store FileStore
 }
 
-func NewImportsGroomer() ImportsGroomer {
-	return &importsGroomer{}
+func NewImportsGroomer(store FileStore) ImportsGroomer {
+	return &importsGroomer{store: store}
 }
 
 func (ig *importsGroomer) RemoveUnusedImportFromDirectory(path string, importsToRemove map[string]string) error {
 	fset := token.NewFileSet()
-	filter := func(info os.FileInfo) bool {
-		return testsFilter(info) && generatedFilter(path, info)
+	// Test and generated files are groomed as well, as they might be instrumented. Only files with unused imports are rewritten.
+	filter := func(fileName string, src []byte) bool {
+		return runtimeConfigFilter(src)
 	}
-	pkgs, err := parser.ParseDir(fset, path, filter, parser.ParseComments)
+	pkgs, err := parseDir(fset, ig.store, path, filter)
 	if err != nil {
 		return fmt.Errorf("failed parsing go files in directory %s: %v", path, err)
 	}
@@ -38,22 +41,31 @@ func (ig *importsGroomer) RemoveUnusedImportFromDirectory(path string, importsTo
 
 func (ig *importsGroomer) RemoveUnusedImportFromPackage(fset *token.FileSet, pkg *ast.Package, importsToRemove map[string]string) error {
 	for fileName, file := range pkg.Files {
-		sourceFile, err := os.OpenFile(fileName, os.O_TRUNC|os.O_WRONLY, 0664)
-		if err != nil {
-			return fmt.Errorf("failed opening file %s: %v", fileName, err)
+		if !hasUnusedImports(file, importsToRemove) {
+			continue
 		}
-		if err := ig.RemoveUnusedImportFromFile(fset, file, sourceFile, importsToRemove); err != nil {
+		var buff bytes.Buffer
+		if err := ig.RemoveUnusedImportFromFile(fset, file, &buff, importsToRemove); err != nil {
 			return fmt.Errorf("failed removing imports %v from file %s: %v", importsToRemove, fileName, err)
 		}
+		if err := ig.store.WriteFile(fileName, buff.Bytes()); err != nil {
+			return fmt.Errorf("failed writing file %s: %v", fileName, err)
+		}
 	}
 	return nil
 }
 
 func (ig *importsGroomer) RemoveUnusedImportFromFile(fset *token.FileSet, file *ast.File, out io.Writer, importsToRemove map[string]string) error {
-	for importToRemove, alias := range importsToRemove {
-		if !astutil.UsesImport(file, importToRemove) {
-			astutil.DeleteNamedImport(fset, file, alias, importToRemove)
+	for _, spec := range unusedAddedImports(file, importsToRemove) {
+		if spec.Comment != nil { // The watermark is not removed along with the import if it is the last one in the declaration
+			removeCommentGroup(file, spec.Comment)
 		}
+		path, _ := strconv.Unquote(spec.Path.Value)
+		var name string
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		astutil.DeleteNamedImport(fset, file, name, path)
 	}
 	// Needed because ast does not support floating comments and deletes them.
 	// In order to preserve all comments we just pre-parse it to dst which treats them as first class citizens.
@@ -64,3 +76,72 @@ func (ig *importsGroomer) RemoveUnusedImportFromFile(fset *token.FileSet, file *
 
 	return decorator.Fprint(out, f)
 }
+
+// hasUnusedImports reports whether any of the imports is added by printracer to the file without being used.
+func hasUnusedImports(file *ast.File, imports map[string]string) bool {
+	return len(unusedAddedImports(file, imports)) > 0
+}
+
+// unusedAddedImports returns the import specs of the file which are added by printracer and not used.
+// Imports added by printracer are marked by a watermark comment (see markAddedImport). Imports of the paths
+// named with the given aliases are considered added as well, as former versions did not mark them. An empty alias stands
+// for an import without a name, which is referred to by the last element of its path.
+// Other imports of the paths, e.g. blank imports or imports of the code reused by the instrumentation, are preserved.
+func unusedAddedImports(file *ast.File, imports map[string]string) []*ast.ImportSpec {
+	var unused []*ast.ImportSpec
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		alias, ok := imports[path]
+		if !ok {
+			continue
+		}
+		name, usedName := "", filepath.Base(path)
+		if spec.Name != nil {
+			name, usedName = spec.Name.Name, spec.Name.Name
+		}
+		if name == "_" || name == "." || !(isMarkedImport(spec) || name == alias) || usesName(file, usedName) {
+			continue
+		}
+		unused = append(unused, spec)
+	}
+	return unused
+}
+
+func isMarkedImport(spec *ast.ImportSpec) bool {
+	if spec.Comment == nil {
+		return false
+	}
+	for _, comment := range spec.Comment.List {
+		if comment.Text == printracerCommentWatermark {
+			return true
+		}
+	}
+	return false
+}
+
+// usesName reports whether the name is used as a package name in a qualified identifier in the file.
+func usesName(file *ast.File, name string) bool {
+	used := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == name && ident.Obj == nil {
+				used = true
+			}
+		}
+		return !used
+	})
+	return used
+}
+
+func removeCommentGroup(file *ast.File, group *ast.CommentGroup) {
+	for i, c := range file.Comments {
+		if c == group {
+			file.Comments = append(file.Comments[:i], file.Comments[i+1:]...)
+			return
+		}
+	}
+}
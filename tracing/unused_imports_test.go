@@ -17,6 +17,8 @@ func TestRemoveUnusedImportsFromFile(t *testing.T) {
 		OutputCode string
 	}{
 		{Name: "RemoveUnusedImportsFromFileWithoutFunctions", InputCode: resultCodeWithoutFunction, OutputCode: codeWithoutFunction},
+		{Name: "RemoveUnusedImportsAddedByFormerVersions", InputCode: "package a\n\nimport prt \"github.com/DimitarPetrov/printracer/rt\"\n\ntype test struct {\n\ta int\n}\n", OutputCode: codeWithoutFunction},
+		{Name: "RemoveUnusedImportsKeepsImportsNotAddedByPrintracer", InputCode: codeWithBlankRtImport, OutputCode: codeWithBlankRtImport},
 	}
 
 	for _, test := range tests {
@@ -27,7 +29,7 @@ func TestRemoveUnusedImportsFromFile(t *testing.T) {
 				t.Fatal(err)
 			}
 			var buff bytes.Buffer
-			if err := NewImportsGroomer().RemoveUnusedImportFromFile(fset, file, &buff, map[string]string{"fmt": "", "runtime": "rt", "crypto/rand": ""}); err != nil {
+			if err := NewImportsGroomer(NewFileStore()).RemoveUnusedImportFromFile(fset, file, &buff, map[string]string{rtPackagePath: rtPackageAlias}); err != nil {
 				t.Fatal(err)
 			}
 
@@ -63,7 +65,11 @@ func TestRemoveUnusedImportsFromDirectory(t *testing.T) {
 		i++
 	}
 
-	if err := NewImportsGroomer().RemoveUnusedImportFromDirectory("test", map[string]string{"fmt": "", "runtime": "rt", "crypto/rand": ""}); err != nil {
+	store := NewFileStore()
+	if err := NewImportsGroomer(store).RemoveUnusedImportFromDirectory("test", map[string]string{rtPackagePath: rtPackageAlias}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(); err != nil {
 		t.Fatal(err)
 	}
 
@@ -79,3 +85,27 @@ func TestRemoveUnusedImportsFromDirectory(t *testing.T) {
 		i++
 	}
 }
+
+func TestRemoveUnusedImportsFromDirectoryRewritesOnlyFilesWithUnusedImports(t *testing.T) {
+	if err := os.Mkdir("test", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll("test"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	const unformattedCode = "package a\n\nfunc  f() {\n}\n"
+	if err := ioutil.WriteFile("test/test_test.go", []byte(unformattedCode), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewFileStore()
+	if err := NewImportsGroomer(store).RemoveUnusedImportFromDirectory("test", map[string]string{rtPackagePath: rtPackageAlias}); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err := store.Changes(); err != nil || len(changes) != 0 {
+		t.Errorf("Assertion failed! Expected no changes but got %v", changes)
+	}
+}
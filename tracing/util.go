@@ -1,374 +1,150 @@
 package tracing
 
 import (
-	"bufio"
-	"fmt"
+	"bytes"
 	"github.com/dave/dst"
+	"go/parser"
 	"go/token"
-	"os"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
 // Filter excluding go test files from directory
-func testsFilter(info os.FileInfo) bool {
-	return !strings.HasSuffix(info.Name(), "_test.go")
+func testsFilter(fileName string) bool {
+	return !strings.HasSuffix(fileName, "_test.go")
 }
 
-// Filter excluding generated go files from directory.
-// Generated file is considered a file which matches one of the following:
-// 1. The name of the file contains "generated"
-// 2. First line of the file contains "generated" or "GENERATED"
-func generatedFilter(path string, info os.FileInfo) bool {
-	if strings.Contains(info.Name(), "generated") {
-		return false
-	}
+// generatedCodeRegex matches the comment marking generated go files. See https://golang.org/s/generatedcode.
+var generatedCodeRegex = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
 
-	f, err := os.Open(path + "/" + info.Name())
-	if err != nil {
-		panic(fmt.Sprintf("Failed opening file %s: %v", path+"/"+info.Name(), err))
+// Filter excluding generated go files from directory.
+// Generated file is considered a file with a line comment matching generatedCodeRegex anywhere before the package clause.
+func generatedFilter(src []byte) bool {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil { // Reported when the file is parsed
+		return true
 	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-	scanner.Scan()
-	line := scanner.Text()
-
-	if strings.Contains(line, "generated") || strings.Contains(line, "GENERATED") {
-		return false
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if generatedCodeRegex.MatchString(comment.Text) {
+				return false
+			}
+		}
 	}
 	return true
 }
 
-// Return dst expresion like: fmt.Printf(args...)
-func newPrintExprWithArgs(args []dst.Expr) *dst.CallExpr {
+// Filter excluding the runtime configuration files generated by printracer from directory.
+// They are never instrumented, while being removed on revert.
+func runtimeConfigFilter(src []byte) bool {
+	return !bytes.HasPrefix(src, []byte(runtimeConfigFileHeader+"\n"))
+}
+
+// Returns dst expression like: prt.funcName(args...) where prt is the name the rt package is imported with
+func newRtCallExpr(rtImport, funcName string, args ...dst.Expr) *dst.CallExpr {
 	return &dst.CallExpr{
 		Fun: &dst.SelectorExpr{
-			X:   &dst.Ident{Name: "fmt"},
-			Sel: &dst.Ident{Name: "Printf"},
+			X:   &dst.Ident{Name: rtImport},
+			Sel: &dst.Ident{Name: funcName},
 		},
 		Args: args,
 	}
 }
 
-func buildEnteringFunctionArgs(f *dst.FuncDecl) []dst.Expr {
-	var enteringStringFormat = "Entering function %s called by %s"
-	args := []dst.Expr{
-		&dst.BasicLit{
-			Kind:  token.STRING,
-			Value: funcNameVarName,
-		},
-		&dst.BasicLit{
-			Kind:  token.STRING,
-			Value: callerFuncNameVarName,
-		},
-	}
-
-	if len(f.Type.Params.List) > 0 {
-		enteringStringFormat += " with args"
-
-		for _, param := range f.Type.Params.List {
-			enteringStringFormat += " (%v)"
-			args = append(args, &dst.BasicLit{
-				Kind:  token.STRING,
-				Value: param.Names[0].Name,
-			})
-		}
-	}
-	args = append(args, &dst.BasicLit{
-		Kind:  token.STRING,
-		Value: callIDVarName,
-	})
-	args = append([]dst.Expr{
-		&dst.BasicLit{
-			Kind:  token.STRING,
-			Value: `"` + enteringStringFormat + `; callID=%s\n"`,
-		},
-	}, args...)
-
-	return args
+// Returns dst expression like: prt.funcName("name", value)
+func newRtFieldExpr(rtImport, funcName, name string, value dst.Expr) *dst.CallExpr {
+	return newRtCallExpr(rtImport, funcName, newStringLit(name), value)
 }
 
-func buildExitFunctionArgs() []dst.Expr {
-	var exitingStringFormat = "Exiting function %s called by %s; callID=%s"
-	return []dst.Expr{
-		&dst.BasicLit{
-			Kind:  token.STRING,
-			Value: `"` + exitingStringFormat + `\n"`,
-		},
-		&dst.BasicLit{
-			Kind:  token.STRING,
-			Value: funcNameVarName,
-		},
-		&dst.BasicLit{
-			Kind:  token.STRING,
-			Value: callerFuncNameVarName,
-		},
-		&dst.BasicLit{
-			Kind:  token.STRING,
-			Value: callIDVarName,
-		},
+func newStringLit(value string) *dst.BasicLit {
+	return &dst.BasicLit{
+		Kind:  token.STRING,
+		Value: strconv.Quote(value),
 	}
 }
 
-// Return dst statement like: varName := "value"
-func newAssignStmt(varName, value string) *dst.AssignStmt {
-	return &dst.AssignStmt{
-		Lhs: []dst.Expr{
-			&dst.Ident{
-				Name: varName,
-			},
-		},
-		Tok: token.DEFINE,
-		Rhs: []dst.Expr{
-			&dst.BasicLit{
-				Kind:  token.STRING,
-				Value: `"` + value + `"`,
-			},
-		},
+func buildEnterArgs(f *function, opts Options) []dst.Expr {
+	var args []dst.Expr
+	if len(f.configVar) > 0 {
+		args = append(args, &dst.Ident{Name: f.configVar})
+	}
+	if f.isLiteral() {
+		args = append(args, newRtCallExpr(f.rtImport, "At", newStringLit(f.position)))
 	}
-}
 
-/* Return dst statement like:
-if funcPcVarName, _, _, ok := runtime.Caller(funcIndex); ok {
-	funcNameVarName = runtime.FuncForPC(funcPcVarName).Name()
-}
-*/
-func newGetFuncNameIfStatement(funcIndex, funcPcVarName, funcNameVarName string) *dst.IfStmt {
-	return &dst.IfStmt{
-		Init: &dst.AssignStmt{
-			Lhs: []dst.Expr{
-				&dst.Ident{
-					Name: funcPcVarName,
-				},
-				&dst.Ident{
-					Name: "_",
-				},
-				&dst.Ident{
-					Name: "_",
-				},
-				&dst.Ident{
-					Name: "ok",
-				},
-			},
-			Tok: token.DEFINE,
-			Rhs: []dst.Expr{
-				&dst.CallExpr{
-					Fun: &dst.SelectorExpr{
-						X: &dst.Ident{
-							Name: "rt",
-						},
-						Sel: &dst.Ident{
-							Name: "Caller",
-						},
-					},
-					Args: []dst.Expr{
-						&dst.BasicLit{
-							Kind:  token.INT,
-							Value: funcIndex,
-						},
-					},
-				},
-			},
-		},
-		Cond: &dst.Ident{
-			Name: "ok",
-		},
-		Body: &dst.BlockStmt{
-			List: []dst.Stmt{
-				&dst.AssignStmt{
-					Lhs: []dst.Expr{
-						&dst.Ident{
-							Name: funcNameVarName,
-						},
-					},
-					Tok: token.ASSIGN,
-					Rhs: []dst.Expr{
-						&dst.CallExpr{
-							Fun: &dst.SelectorExpr{
-								X: &dst.CallExpr{
-									Fun: &dst.SelectorExpr{
-										X: &dst.Ident{
-											Name: "rt",
-										},
-										Sel: &dst.Ident{
-											Name: "FuncForPC",
-										},
-									},
-									Args: []dst.Expr{
-										&dst.Ident{
-											Name: funcPcVarName,
-										},
-									},
-								},
-								Sel: &dst.Ident{
-									Name: "Name",
-								},
-							},
-						},
-					},
-				},
-			},
-		},
+	if opts.PrintReceivers && f.recv != nil && len(f.recv.List) > 0 {
+		recv := f.recv.List[0]
+		if len(recv.Names) == 0 || recv.Names[0].Name == "_" {
+			args = append(args, newRtFieldExpr(f.rtImport, "Receiver", "", &dst.Ident{Name: "nil"}))
+		} else if f.redacted[recv.Names[0].Name] {
+			args = append(args, newRtCallExpr(f.rtImport, "RedactedReceiver", newStringLit(recv.Names[0].Name)))
+		} else {
+			args = append(args, newRtFieldExpr(f.rtImport, "Receiver", recv.Names[0].Name, &dst.Ident{Name: recv.Names[0].Name}))
+		}
 	}
-}
 
-// Returns dst statement like:
-// idBytes := make([]byte, 16)
-func newMakeByteSliceStmt() *dst.AssignStmt {
-	return &dst.AssignStmt{
-		Lhs: []dst.Expr{
-			&dst.Ident{
-				Name: "idBytes",
-			},
-		},
-		Tok: token.DEFINE,
-		Rhs: []dst.Expr{
-			&dst.CallExpr{
-				Fun: &dst.Ident{
-					Name: "make",
-				},
-				Args: []dst.Expr{
-					&dst.ArrayType{
-						Elt: &dst.Ident{
-							Name: "byte",
-						},
-					},
-					&dst.BasicLit{
-						Kind:  token.INT,
-						Value: "16",
-					},
-				},
-			},
-		},
+	for _, param := range f.typ.Params.List {
+		if len(param.Names) == 0 {
+			args = append(args, newRtFieldExpr(f.rtImport, "Arg", "", &dst.Ident{Name: "nil"}))
+			continue
+		}
+		_, variadic := param.Type.(*dst.Ellipsis)
+		for _, name := range param.Names {
+			switch {
+			case name.Name == "_":
+				args = append(args, newRtFieldExpr(f.rtImport, "Arg", "", &dst.Ident{Name: "nil"}))
+			case f.redacted[name.Name] && variadic:
+				args = append(args, newRtCallExpr(f.rtImport, "Redacted", newStringLit(name.Name+"...")))
+			case f.redacted[name.Name]:
+				args = append(args, newRtCallExpr(f.rtImport, "Redacted", newStringLit(name.Name)))
+			case variadic:
+				args = append(args, newRtFieldExpr(f.rtImport, f.argFunc(name.Name), name.Name+"...", &dst.Ident{Name: name.Name}))
+			case isTestingType(param.Type, f.testingImport):
+				args = append(args, newRtFieldExpr(f.rtImport, "Test", name.Name, &dst.Ident{Name: name.Name}))
+			default:
+				args = append(args, newRtFieldExpr(f.rtImport, f.argFunc(name.Name), name.Name, &dst.Ident{Name: name.Name}))
+			}
+		}
 	}
+	return args
 }
 
-// Returns dst statement like:
-// _, _ = rand.Read(idBytes)
-func newRandReadStmt() *dst.AssignStmt {
-	return &dst.AssignStmt{
-		Lhs: []dst.Expr{
-			&dst.Ident{
-				Name: "_",
-			},
-			&dst.Ident{
-				Name: "_",
-			},
-		},
-		Tok: token.ASSIGN,
-		Rhs: []dst.Expr{
-			&dst.CallExpr{
-				Fun: &dst.SelectorExpr{
-					X: &dst.Ident{
-						Name: "rand",
-					},
-					Sel: &dst.Ident{
-						Name: "Read",
-					},
-				},
-				Args: []dst.Expr{
-					&dst.Ident{
-						Name: "idBytes",
-					},
-				},
-			},
-		},
+func buildExitArgs(f *function) []dst.Expr {
+	var args []dst.Expr
+	if f.typ.Results == nil {
+		return args
+	}
+	for _, result := range f.typ.Results.List {
+		for _, name := range result.Names {
+			printedName := name.Name
+			if f.generatedResults[name.Name] {
+				printedName = ""
+			}
+			resultFunc := "Result"
+			if f.redacted[name.Name] {
+				resultFunc = "RedactedResult"
+			}
+			args = append(args, newRtFieldExpr(f.rtImport, resultFunc, printedName, &dst.UnaryExpr{
+				Op: token.AND,
+				X:  &dst.Ident{Name: name.Name},
+			}))
+		}
 	}
+	return args
 }
 
 // Returns dst statement like:
-// callID := fmt.Sprintf("%x-%x-%x-%x-%x", idBytes[0:4], idBytes[4:6], idBytes[6:8], idBytes[8:10], idBytes[10:])
-func newParseUUIDFromByteSliceStmt(callIDVarName string) *dst.AssignStmt {
-	return &dst.AssignStmt{
-		Lhs: []dst.Expr{
-			&dst.Ident{
-				Name: callIDVarName,
-			},
-		},
-		Tok: token.DEFINE,
-		Rhs: []dst.Expr{
-			&dst.CallExpr{
-				Fun: &dst.SelectorExpr{
-					X: &dst.Ident{
-						Name: "fmt",
-					},
-					Sel: &dst.Ident{
-						Name: "Sprintf",
-					},
-				},
-				Args: []dst.Expr{
-					&dst.BasicLit{
-						Kind:  token.STRING,
-						Value: "\"%x-%x-%x-%x-%x\"",
-					},
-					&dst.SliceExpr{
-						X: &dst.Ident{
-							Name: "idBytes",
-						},
-						Low: &dst.BasicLit{
-							Kind:  token.INT,
-							Value: "0",
-						},
-						High: &dst.BasicLit{
-							Kind:  token.INT,
-							Value: "4",
-						},
-						Slice3: false,
-					},
-					&dst.SliceExpr{
-						X: &dst.Ident{
-							Name: "idBytes",
-						},
-						Low: &dst.BasicLit{
-							Kind:  token.INT,
-							Value: "4",
-						},
-						High: &dst.BasicLit{
-							Kind:  token.INT,
-							Value: "6",
-						},
-						Slice3: false,
-					},
-					&dst.SliceExpr{
-						X: &dst.Ident{
-							Name: "idBytes",
-						},
-						Low: &dst.BasicLit{
-							Kind:  token.INT,
-							Value: "6",
-						},
-						High: &dst.BasicLit{
-							Kind:  token.INT,
-							Value: "8",
-						},
-						Slice3: false,
-					},
-					&dst.SliceExpr{
-						X: &dst.Ident{
-							Name: "idBytes",
-						},
-						Low: &dst.BasicLit{
-							Kind:  token.INT,
-							Value: "8",
-						},
-						High: &dst.BasicLit{
-							Kind:  token.INT,
-							Value: "10",
-						},
-						Slice3: false,
-					},
-					&dst.SliceExpr{
-						X: &dst.Ident{
-							Name: "idBytes",
-						},
-						Low: &dst.BasicLit{
-							Kind:  token.INT,
-							Value: "10",
-						},
-						Slice3: false,
-					},
-				},
-			},
+// defer prt.Enter(prt.Arg("i", i), prt.Arg("b", b))(prt.Result("", &printracerResult0))
+// Enter prints the invocation and the deferred function it returns prints the return along with the results.
+func newEnterDeferStmt(f *function, opts Options) *dst.DeferStmt {
+	return &dst.DeferStmt{
+		Call: &dst.CallExpr{
+			Fun:  newRtCallExpr(f.rtImport, "Enter", buildEnterArgs(f, opts)...),
+			Args: buildExitArgs(f),
 		},
 	}
 }
@@ -0,0 +1,27 @@
+package tracing
+
+import "testing"
+
+func TestGeneratedFilter(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Code      string
+		Generated bool
+	}{
+		{Name: "Marker", Code: "// Code generated by mockgen. DO NOT EDIT.\n\npackage a\n", Generated: true},
+		{Name: "MarkerAfterLicenseHeader", Code: "// Copyright 2020 The Authors.\n// Licensed under the Apache License.\n\n// Code generated by protoc-gen-go. DO NOT EDIT.\n\n// Package a does things.\npackage a\n", Generated: true},
+		{Name: "MarkerInBlockComment", Code: "/* Code generated by mockgen. DO NOT EDIT. */\n\npackage a\n", Generated: false},
+		{Name: "MarkerAfterPackageClause", Code: "package a\n\n// Code generated by mockgen. DO NOT EDIT.\n", Generated: false},
+		{Name: "MarkerWithoutPeriod", Code: "// Code generated by mockgen. DO NOT EDIT\n\npackage a\n", Generated: false},
+		{Name: "FirstLineMentioningGenerated", Code: "// Handlers for generated requests.\npackage a\n", Generated: false},
+		{Name: "WithoutComments", Code: "package a\n", Generated: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if generated := !generatedFilter([]byte(test.Code)); generated != test.Generated {
+				t.Errorf("Assertion failed! Expected generated to be %t got %t", test.Generated, generated)
+			}
+		})
+	}
+}
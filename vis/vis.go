@@ -7,6 +7,7 @@ import (
 	"html/template"
 	"math"
 	"os"
+	"strings"
 )
 
 const reportTemplate = `
@@ -45,6 +46,7 @@ const reportTemplate = `
             <th scope="col">#</th>
             <th scope="col">Arguments</th>
 			<th scope="col">Call ID</th>
+			<th scope="col">Duration</th>
         </tr>
         </thead>
         <tbody>
@@ -57,6 +59,9 @@ const reportTemplate = `
 			<td>
 				<pre style="max-height: 1000px; margin-bottom: 0; border: 1px solid #eee;"><code id="callID-{{$i}}">{{ $e.CallID }}</code></pre>
 			</td>
+			<td>
+				<pre style="max-height: 1000px; margin-bottom: 0; border: 1px solid #eee;"><code id="duration-{{$i}}">{{ $e.Duration }}</code></pre>
+			</td>
         </tr>
         {{ end }}
         </tbody>
@@ -146,6 +151,8 @@ func (s *stack) Empty() bool {
 type TableRow struct {
 	Args   string
 	CallID string
+	// Duration of the call. Set on returning rows of traces with durations only.
+	Duration string
 }
 
 type templateData struct {
@@ -154,28 +161,107 @@ type templateData struct {
 	MetaJSON  template.JS
 }
 
+// maxMessageLen is the maximum length in characters of the values printed on an arrow of the sequence diagram.
+// Longer values are shortened, while they are listed in full in the table of calls.
+const maxMessageLen = 80
+
+// lineBreaks replaces the line breaks in the names and messages of the sequence diagram, each line of which is a record.
+var lineBreaks = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
 type sequenceDiagramData struct {
 	data  bytes.Buffer
 	count int
 }
 
-func (r *sequenceDiagramData) addFunctionInvocation(source, target string) {
-	r.addRecord(source, "->", target)
+func (r *sequenceDiagramData) addFunctionInvocation(source, target, method string) {
+	r.addRecord(source, "->", target, method)
 }
 
-func (r *sequenceDiagramData) addFunctionReturn(source, target string) {
-	r.addRecord(source, "-->", target)
+func (r *sequenceDiagramData) addFunctionReturn(source, target string, event *parser.ReturningEvent) {
+	message := shorten(event.Results, maxMessageLen)
+	if event.Duration > 0 {
+		message = strings.TrimSpace(message + " in " + event.Duration.String())
+	}
+	r.addRecord(source, "-->", target, message)
 }
 
-func (r *sequenceDiagramData) addRecord(source, operation, target string) {
+func (r *sequenceDiagramData) addRecord(source, operation, target, message string) {
 	r.count++
-	r.data.WriteString(fmt.Sprintf("\"%s\"%s\"%s\": (%d)\n", source, operation, target, r.count))
+	r.data.WriteString(fmt.Sprintf("\"%s\"%s\"%s\": (%d)", participantName(source), operation, participantName(target), r.count))
+	if message = lineBreaks.Replace(message); len(message) > 0 {
+		r.data.WriteString(" " + message)
+	}
+	r.data.WriteString("\n")
+}
+
+// participantName returns the name of the participant as it can be quoted in the sequence diagram.
+func participantName(name string) string {
+	return strings.ReplaceAll(lineBreaks.Replace(name), `"`, "'")
+}
+
+// shorten shortens the text to maxLen characters, marking it with ... if shortened.
+func shorten(text string, maxLen int) string {
+	runes := []rune(text)
+	if len(runes) <= maxLen {
+		return text
+	}
+	return string(runes[:maxLen]) + "..."
 }
 
 func (r *sequenceDiagramData) String() string {
 	return r.data.String()
 }
 
+type openInvocation struct {
+	event  *parser.InvocationEvent
+	source string
+	target string
+}
+
+// participants resolves the participants of the sequence diagram.
+// Functions are drawn as a participant per function, while methods invoked on a printed receiver
+// are drawn as a participant per receiver instance (e.g. main.(*T)@0xc00001c030).
+type participants struct {
+	open []openInvocation
+}
+
+// invoked returns the source and target participants of the invocation and the method name when the target is an instance.
+func (p *participants) invoked(event *parser.InvocationEvent) (string, string, string) {
+	source := p.caller(event)
+	target, method := event.GetCallee(), ""
+	if len(event.Receiver) > 0 {
+		if i := strings.LastIndex(target, "."); i != -1 {
+			target, method = target[:i], target[i+1:]
+		}
+		target = target + "@" + event.Receiver
+	}
+	p.open = append(p.open, openInvocation{event: event, source: source, target: target})
+	return source, target, method
+}
+
+// returned returns the source and target participants of the return, i.e. the reversed participants of the invocation.
+func (p *participants) returned(event *parser.ReturningEvent) (string, string) {
+	for i := len(p.open) - 1; i >= 0; i-- {
+		if p.open[i].event.GetCallID() == event.GetCallID() {
+			invocation := p.open[i]
+			p.open = append(p.open[:i], p.open[i+1:]...)
+			return invocation.target, invocation.source
+		}
+	}
+	return event.GetCallee(), event.GetCaller()
+}
+
+// caller returns the participant of the most recent unfinished invocation of the function on the goroutine of the event
+// or the function itself.
+func (p *participants) caller(event parser.FuncEvent) string {
+	for i := len(p.open) - 1; i >= 0; i-- {
+		if p.open[i].event.GetCallee() == event.GetCaller() && p.open[i].event.GetGoroutineID() == event.GetGoroutineID() {
+			return p.open[i].target
+		}
+	}
+	return event.GetCaller()
+}
+
 func (v *visualizer) constructTemplateData(events []parser.FuncEvent, maxDepth int, startingFunc string) (templateData, error) {
 	if maxDepth == math.MaxInt32 && len(startingFunc) == 0 {
 		return v.constructTemplateDataGraph(events)
@@ -185,6 +271,7 @@ func (v *visualizer) constructTemplateData(events []parser.FuncEvent, maxDepth i
 
 func (v *visualizer) constructTemplateDataGraph(events []parser.FuncEvent) (templateData, error) {
 	diagramData := &sequenceDiagramData{}
+	participants := &participants{}
 
 	var tableRows []TableRow
 
@@ -192,17 +279,15 @@ func (v *visualizer) constructTemplateDataGraph(events []parser.FuncEvent) (temp
 		event := events[i]
 		switch event := event.(type) {
 		case *parser.InvocationEvent:
-			diagramData.addFunctionInvocation(event.GetCaller(), event.GetCallee())
+			diagramData.addFunctionInvocation(participants.invoked(event))
 			tableRows = append(tableRows, TableRow{
 				Args:   fmt.Sprintf("calling %s", event.Args),
 				CallID: event.GetCallID(),
 			})
 		case *parser.ReturningEvent:
-			diagramData.addFunctionReturn(event.GetCallee(), event.GetCaller())
-			tableRows = append(tableRows, TableRow{
-				Args:   "returning",
-				CallID: event.GetCallID(),
-			})
+			source, target := participants.returned(event)
+			diagramData.addFunctionReturn(source, target, event)
+			tableRows = append(tableRows, newReturningTableRow(event))
 		}
 	}
 
@@ -228,7 +313,12 @@ func (v *visualizer) constructTemplateDataLinearly(events []parser.FuncEvent, ma
 		if !found {
 			return templateData{}, fmt.Errorf("could not find functions called by %s", startingFunc)
 		}
+	}
 
+	// Only calls on the goroutine of the starting call are part of its call stack.
+	events = goroutineEvents(events)
+
+	if len(startingFunc) > 0 {
 		for i := 1; i < len(events); i++ {
 			if _, ok := events[i].(*parser.ReturningEvent); ok && events[i].GetCaller() == startingFunc {
 				events = events[:i+1]
@@ -238,9 +328,10 @@ func (v *visualizer) constructTemplateDataLinearly(events []parser.FuncEvent, ma
 	}
 
 	stack := stack(make([]parser.FuncEvent, 0, len(events)))
+	participants := &participants{}
 	var tableRows []TableRow
 
-	diagramData.addFunctionInvocation(events[0].GetCaller(), events[0].GetCallee())
+	diagramData.addFunctionInvocation(participants.invoked(events[0].(*parser.InvocationEvent)))
 	stack.Push(events[0])
 	tableRows = append(tableRows, TableRow{
 		Args:   fmt.Sprintf("calling %s", events[0].(*parser.InvocationEvent).Args),
@@ -257,7 +348,7 @@ func (v *visualizer) constructTemplateDataLinearly(events []parser.FuncEvent, ma
 			if stack.Length() < maxDepth {
 				prev := stack.Peek().(*parser.InvocationEvent)
 				if prev.GetCallee() == event.GetCaller() {
-					diagramData.addFunctionInvocation(event.GetCaller(), event.GetCallee())
+					diagramData.addFunctionInvocation(participants.invoked(event))
 					tableRows = append(tableRows, TableRow{
 						Args:   fmt.Sprintf("calling %s", event.Args),
 						CallID: event.GetCallID(),
@@ -268,11 +359,9 @@ func (v *visualizer) constructTemplateDataLinearly(events []parser.FuncEvent, ma
 		case *parser.ReturningEvent:
 			if stack.Peek().GetCallee() == event.GetCallee() {
 				_ = stack.Pop()
-				diagramData.addFunctionReturn(event.GetCallee(), event.GetCaller())
-				tableRows = append(tableRows, TableRow{
-					Args:   "returning",
-					CallID: event.GetCallID(),
-				})
+				source, target := participants.returned(event)
+				diagramData.addFunctionReturn(source, target, event)
+				tableRows = append(tableRows, newReturningTableRow(event))
 			}
 		}
 	}
@@ -282,3 +371,33 @@ func (v *visualizer) constructTemplateDataLinearly(events []parser.FuncEvent, ma
 		TableRows: tableRows,
 	}, nil
 }
+
+// goroutineEvents returns the events which happened on the goroutine of the first event.
+// Events of traces without goroutine IDs are all considered to happen on the same goroutine.
+func goroutineEvents(events []parser.FuncEvent) []parser.FuncEvent {
+	if len(events) == 0 {
+		return events
+	}
+	goroutineID := events[0].GetGoroutineID()
+	result := make([]parser.FuncEvent, 0, len(events))
+	for _, event := range events {
+		if event.GetGoroutineID() == goroutineID {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+func newReturningTableRow(event *parser.ReturningEvent) TableRow {
+	row := TableRow{
+		Args:   "returning",
+		CallID: event.GetCallID(),
+	}
+	if len(event.Results) > 0 {
+		row.Args = fmt.Sprintf("returning %s", event.Results)
+	}
+	if event.Duration > 0 {
+		row.Duration = event.Duration.String()
+	}
+	return row
+}
@@ -3,12 +3,14 @@ package vis
 import (
 	"bytes"
 	"github.com/DimitarPetrov/printracer/parser"
+	"html/template"
 	"io/ioutil"
 	"math"
 	"os"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 var inputEvents = []parser.FuncEvent{
@@ -40,9 +42,11 @@ var inputEvents = []parser.FuncEvent{
 		CallID: "a019a297-0a6e-a792-0e3f-23c33a44622f",
 	},
 	&parser.ReturningEvent{
-		Caller: "main.foo",
-		Callee: "main.bar",
-		CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514",
+		Caller:   "main.foo",
+		Callee:   "main.bar",
+		CallID:   "6c294dfd-4c6a-39b1-474e-314bee73f514",
+		Results:  "with results (5) (<nil>)",
+		Duration: 1500 * time.Microsecond,
 	},
 	&parser.ReturningEvent{
 		Caller: "main.main",
@@ -61,7 +65,7 @@ var fullDiagram = `"runtime.main"->"main.main": (1)
 "main.foo"->"main.bar": (3)
 "main.bar"->"main.baz": (4)
 "main.baz"-->"main.bar": (5)
-"main.bar"-->"main.foo": (6)
+"main.bar"-->"main.foo": (6) with results (5) (<nil>) in 1.5ms
 "main.foo"-->"main.main": (7)
 "main.main"-->"runtime.main": (8)
 `
@@ -71,7 +75,7 @@ var fullTableRows = []TableRow{
 	{Args: "calling with args (test string)", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514"},
 	{Args: "calling ", CallID: "a019a297-0a6e-a792-0e3f-23c33a44622f"},
 	{Args: "returning", CallID: "a019a297-0a6e-a792-0e3f-23c33a44622f"},
-	{Args: "returning", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514"},
+	{Args: "returning with results (5) (<nil>)", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514", Duration: "1.5ms"},
 	{Args: "returning", CallID: "973355a9-2ec6-095c-9137-7a1081ac0a5f"},
 	{Args: "returning", CallID: "1d8ca74e-c860-8a75-fc36-fe6d34350f0c"},
 }
@@ -91,21 +95,21 @@ var tableRowsWith2DepthLimit = []TableRow{
 var diagramWithFooStartingFunc = `"main.foo"->"main.bar": (1)
 "main.bar"->"main.baz": (2)
 "main.baz"-->"main.bar": (3)
-"main.bar"-->"main.foo": (4)
+"main.bar"-->"main.foo": (4) with results (5) (<nil>) in 1.5ms
 `
 var tableRowsWithFooStartingFunc = []TableRow{
 	{Args: "calling with args (test string)", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514"},
 	{Args: "calling ", CallID: "a019a297-0a6e-a792-0e3f-23c33a44622f"},
 	{Args: "returning", CallID: "a019a297-0a6e-a792-0e3f-23c33a44622f"},
-	{Args: "returning", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514"},
+	{Args: "returning with results (5) (<nil>)", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514", Duration: "1.5ms"},
 }
 
 var diagramWithFooStartingFuncAnd2DepthLimit = `"main.foo"->"main.bar": (1)
-"main.bar"-->"main.foo": (2)
+"main.bar"-->"main.foo": (2) with results (5) (<nil>) in 1.5ms
 `
 var tableRowsWithFooStartingFuncAnd2DepthLimit = []TableRow{
 	{Args: "calling with args (test string)", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514"},
-	{Args: "returning", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514"},
+	{Args: "returning with results (5) (<nil>)", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514", Duration: "1.5ms"},
 }
 
 func TestVisualizerConstructTemplateData(t *testing.T) {
@@ -140,6 +144,111 @@ func TestVisualizerConstructTemplateData(t *testing.T) {
 	}
 }
 
+var inputEventsWithReceivers = []parser.FuncEvent{
+	&parser.InvocationEvent{
+		Caller:   "main.main",
+		Callee:   "main.(*T).foo",
+		CallID:   "973355a9-2ec6-095c-9137-7a1081ac0a5f",
+		Receiver: "0xc00001c030",
+	},
+	&parser.InvocationEvent{
+		Caller:   "main.(*T).foo",
+		Callee:   "main.(*T).bar",
+		CallID:   "6c294dfd-4c6a-39b1-474e-314bee73f514",
+		Receiver: "0xc00001c040",
+	},
+	&parser.ReturningEvent{
+		Caller: "main.(*T).foo",
+		Callee: "main.(*T).bar",
+		CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514",
+	},
+	&parser.InvocationEvent{
+		Caller:   "main.(*T).foo",
+		Callee:   "main.(*T).bar",
+		CallID:   "a019a297-0a6e-a792-0e3f-23c33a44622f",
+		Receiver: "0xc00001c030",
+	},
+	&parser.ReturningEvent{
+		Caller: "main.(*T).foo",
+		Callee: "main.(*T).bar",
+		CallID: "a019a297-0a6e-a792-0e3f-23c33a44622f",
+	},
+	&parser.ReturningEvent{
+		Caller: "main.main",
+		Callee: "main.(*T).foo",
+		CallID: "973355a9-2ec6-095c-9137-7a1081ac0a5f",
+	},
+}
+
+var diagramWithReceivers = `"main.main"->"main.(*T)@0xc00001c030": (1) foo
+"main.(*T)@0xc00001c030"->"main.(*T)@0xc00001c040": (2) bar
+"main.(*T)@0xc00001c040"-->"main.(*T)@0xc00001c030": (3)
+"main.(*T)@0xc00001c030"->"main.(*T)@0xc00001c030": (4) bar
+"main.(*T)@0xc00001c030"-->"main.(*T)@0xc00001c030": (5)
+"main.(*T)@0xc00001c030"-->"main.main": (6)
+`
+
+func TestVisualizerConstructTemplateDataWithReceivers(t *testing.T) {
+	tests := []struct {
+		Name     string
+		MaxDepth int
+	}{
+		{Name: "Graph", MaxDepth: math.MaxInt32},
+		{Name: "Linear", MaxDepth: 3},
+	}
+
+	visualizer := visualizer{}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			diagramData, err := visualizer.constructTemplateData(inputEventsWithReceivers, test.MaxDepth, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diagramData.Diagram != diagramWithReceivers {
+				t.Errorf("Assertion failed! Expected diagram data: %s bug got: %s", diagramWithReceivers, diagramData.Diagram)
+			}
+		})
+	}
+}
+
+var inputEventsFromMultipleGoroutines = []parser.FuncEvent{
+	&parser.InvocationEvent{Caller: "main.worker", Callee: "main.foo", CallID: "973355a9-2ec6-095c-9137-7a1081ac0a5f", GoroutineID: "2"},
+	&parser.InvocationEvent{Caller: "main.worker", Callee: "main.foo", CallID: "1d8ca74e-c860-8a75-fc36-fe6d34350f0c", GoroutineID: "3"},
+	&parser.InvocationEvent{Caller: "main.foo", Callee: "main.bar", CallID: "a019a297-0a6e-a792-0e3f-23c33a44622f", GoroutineID: "3"},
+	&parser.InvocationEvent{Caller: "main.foo", Callee: "main.bar", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514", GoroutineID: "2"},
+	&parser.ReturningEvent{Caller: "main.foo", Callee: "main.bar", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514", GoroutineID: "2"},
+	&parser.ReturningEvent{Caller: "main.worker", Callee: "main.foo", CallID: "973355a9-2ec6-095c-9137-7a1081ac0a5f", GoroutineID: "2"},
+	&parser.ReturningEvent{Caller: "main.foo", Callee: "main.bar", CallID: "a019a297-0a6e-a792-0e3f-23c33a44622f", GoroutineID: "3"},
+	&parser.ReturningEvent{Caller: "main.worker", Callee: "main.foo", CallID: "1d8ca74e-c860-8a75-fc36-fe6d34350f0c", GoroutineID: "3"},
+}
+
+var diagramOfFirstGoroutine = `"main.worker"->"main.foo": (1)
+"main.foo"->"main.bar": (2)
+"main.bar"-->"main.foo": (3)
+"main.foo"-->"main.worker": (4)
+`
+var tableRowsOfFirstGoroutine = []TableRow{
+	{Args: "calling ", CallID: "973355a9-2ec6-095c-9137-7a1081ac0a5f"},
+	{Args: "calling ", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514"},
+	{Args: "returning", CallID: "6c294dfd-4c6a-39b1-474e-314bee73f514"},
+	{Args: "returning", CallID: "973355a9-2ec6-095c-9137-7a1081ac0a5f"},
+}
+
+func TestVisualizerConstructTemplateDataFollowsGoroutine(t *testing.T) {
+	visualizer := visualizer{}
+	diagramData, err := visualizer.constructTemplateData(inputEventsFromMultipleGoroutines, math.MaxInt32, "main.worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagramData.Diagram != diagramOfFirstGoroutine {
+		t.Errorf("Assertion failed! Expected diagram data: %s bug got: %s", diagramOfFirstGoroutine, diagramData.Diagram)
+	}
+	if !reflect.DeepEqual(diagramData.TableRows, tableRowsOfFirstGoroutine) {
+		t.Errorf("Assertion failed! Expected args: %v bug got: %v", tableRowsOfFirstGoroutine, diagramData.TableRows)
+	}
+}
+
 func TestVisualize(t *testing.T) {
 	tests := []struct {
 		Name         string
@@ -171,18 +280,49 @@ func TestVisualize(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			encodedDiagram := strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(test.Diagram, "->", `-\x3e`), "\n", `\n`), `"`, `\x22`)
-			if !bytes.Contains(html, []byte(encodedDiagram)) {
+			// Escaping of JS strings by html/template differs between go versions (\x22 vs \u0022).
+			decodedHTML := strings.NewReplacer(`\x3c`, "<", `\u003c`, "<", `\x3e`, ">", `\u003e`, ">", `\x22`, `"`, `\u0022`, `"`, `\n`, "\n").Replace(string(html))
+			if !strings.Contains(decodedHTML, test.Diagram) {
 				t.Error("Assertion failed! Expected html file to contain diagram data")
 			}
 			for _, row := range test.TableRows {
-				if !bytes.Contains(html, []byte(row.Args)) {
+				if !bytes.Contains(html, []byte(template.HTMLEscapeString(row.Args))) {
 					t.Errorf("Assertion failed! Expected html file to contain arg %s", row.Args)
 				}
 				if !bytes.Contains(html, []byte(row.CallID)) {
 					t.Errorf("Assertion failed! Expected html file to contain callID %s", row.CallID)
 				}
+				if !bytes.Contains(html, []byte(row.Duration)) {
+					t.Errorf("Assertion failed! Expected html file to contain duration %s", row.Duration)
+				}
 			}
 		})
 	}
 }
+
+var inputEventsWithMultilineValues = []parser.FuncEvent{
+	&parser.InvocationEvent{Caller: "main.main", Callee: "main.T.foo", CallID: "1", Receiver: "{a\nb \"c\"}"},
+	&parser.ReturningEvent{Caller: "main.main", Callee: "main.T.foo", CallID: "1", Results: "with results (line1\nA->B: x)"},
+	&parser.InvocationEvent{Caller: "main.main", Callee: "main.bar", CallID: "2"},
+	&parser.ReturningEvent{Caller: "main.main", Callee: "main.bar", CallID: "2", Results: "with results (" + strings.Repeat("a", 100) + ")", Duration: time.Millisecond},
+}
+
+var diagramWithMultilineValues = `"main.main"->"main.T@{a b 'c'}": (1) foo
+"main.T@{a b 'c'}"-->"main.main": (2) with results (line1 A->B: x)
+"main.main"->"main.bar": (3)
+"main.bar"-->"main.main": (4) with results (` + strings.Repeat("a", 66) + `... in 1ms
+`
+
+func TestVisualizerConstructTemplateDataEscapesValues(t *testing.T) {
+	visualizer := visualizer{}
+	diagramData, err := visualizer.constructTemplateData(inputEventsWithMultilineValues, math.MaxInt32, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagramData.Diagram != diagramWithMultilineValues {
+		t.Errorf("Assertion failed! Expected diagram data: %s bug got: %s", diagramWithMultilineValues, diagramData.Diagram)
+	}
+	if diagramData.TableRows[1].Args != "returning with results (line1\nA->B: x)" {
+		t.Errorf("Assertion failed! Expected the results in full in the table but got: %s", diagramData.TableRows[1].Args)
+	}
+}